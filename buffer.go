@@ -0,0 +1,112 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/youthlin/stream/types"
+)
+
+// closer 需要释放资源的数据源. 终止操作结束(包括提前结束和 panic)时会调用 close
+type closer interface {
+	close()
+}
+
+// Buffered 在单独的 goroutine 中执行上游的数据源和操作, 通过容量为 n 的通道预先取出元素,
+// 使慢的生产者(如读磁盘)和慢的消费者(如写网络)可以同时工作.
+// 下游提前结束(如 Limit, FindFirst)时会停止并等待该 goroutine; 上游的 panic 会在终止操作的 goroutine 上重新抛出.
+// Buffered runs the upstream in its own goroutine, which sends elements to a channel of capacity n.
+// The goroutine is stopped when the downstream finishes early,
+// and a panic in the upstream is re-raised on the goroutine of the terminal operate.
+func (s *stream) Buffered(n int) Stream {
+	if n < 0 {
+		n = 0
+	}
+	return newHead(func() iterator {
+		return newBufferedIt(s, n)
+	})
+}
+
+// bufferedIt 从通道中读取上游 goroutine 产生的元素
+type bufferedIt struct {
+	ch       chan types.T
+	done     chan struct{}
+	stop     sync.Once
+	wg       sync.WaitGroup
+	panicVal interface{} // 上游的 panic, 在 ch 关闭后读取
+	next     types.T
+	peeked   bool
+}
+
+func newBufferedIt(upstream *stream, n int) *bufferedIt {
+	source := upstream.open() // 在调用方 goroutine 上打开, ErrStreamConsumed 直接抛出
+	b := &bufferedIt{
+		ch:   make(chan types.T, n),
+		done: make(chan struct{}),
+	}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		defer close(b.ch)
+		defer func() {
+			b.panicVal = recover()
+		}()
+		if c, ok := source.(closer); ok {
+			defer c.close()
+		}
+		upstream.run(source, newTerminalStage(func(e types.T) {
+			select {
+			case b.ch <- e:
+			case <-b.done:
+			}
+		}, canFinish(b.stopped)))
+	}()
+	return b
+}
+
+func (b *bufferedIt) stopped() bool {
+	select {
+	case <-b.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func (b *bufferedIt) GetSizeIfKnown() int64 {
+	return unknownSize
+}
+
+func (b *bufferedIt) HasNext() bool {
+	if b.peeked {
+		return true
+	}
+	e, ok := <-b.ch
+	if !ok {
+		if b.panicVal != nil {
+			p := b.panicVal
+			b.panicVal = nil
+			panic(p)
+		}
+		return false
+	}
+	b.next, b.peeked = e, true
+	return true
+}
+
+// peek 返回下一个元素但不移动, 调用前 HasNext 需要返回 true
+func (b *bufferedIt) peek() types.T {
+	return b.next
+}
+
+func (b *bufferedIt) Next() types.T {
+	b.HasNext()
+	b.peeked = false
+	return b.next
+}
+
+func (b *bufferedIt) close() {
+	b.stop.Do(func() {
+		close(b.done)
+	})
+	b.wg.Wait()
+}
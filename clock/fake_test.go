@@ -0,0 +1,29 @@
+package clock_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/youthlin/stream/clock"
+)
+
+func ExampleFake() {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		clk.Sleep(time.Minute)
+		fmt.Println("woke up at", clk.Now().Sub(start))
+	}()
+	clk.BlockUntil(1) // wait for the goroutine to sleep
+	clk.Advance(30 * time.Second)
+	clk.Advance(45 * time.Second)
+	<-done
+
+	t := clk.NewTimer(time.Second)
+	fmt.Println(t.Stop(), t.Stop())
+	// Output:
+	// woke up at 1m15s
+	// true false
+}
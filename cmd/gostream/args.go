@@ -0,0 +1,93 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var errHelp = errors.New("help requested")
+
+// config 命令行参数
+type config struct {
+	input    string // jsonl, csv or empty (detect by file extension)
+	output   string
+	files    []string
+	pipeline pipeline
+}
+
+// parseArgs 按出现顺序解析参数. 流水线参数的顺序即操作的顺序, 所以不能使用 flag 包
+func parseArgs(args []string) (*config, error) {
+	cfg := &config{output: formatJSONL}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "-" || !strings.HasPrefix(arg, "-") {
+			cfg.files = append(cfg.files, arg)
+			continue
+		}
+		if arg == "--" {
+			cfg.files = append(cfg.files, args[i+1:]...)
+			break
+		}
+		name := strings.TrimLeft(arg, "-")
+		if name == "h" || name == "help" {
+			return nil, errHelp
+		}
+		value, hasValue := "", false
+		if j := strings.Index(name, "="); j >= 0 {
+			name, value, hasValue = name[:j], name[j+1:], true
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag --%s needs an argument", name)
+			}
+			i++
+			value = args[i]
+		}
+		if err := cfg.set(name, value); err != nil {
+			return nil, fmt.Errorf("--%s %s: %w", name, value, err)
+		}
+	}
+	return cfg, nil
+}
+
+func (cfg *config) set(name, value string) error {
+	switch name {
+	case "input":
+		if value != formatJSONL && value != formatCSV {
+			return errors.New("input format must be jsonl or csv")
+		}
+		cfg.input = value
+	case "output":
+		if value != formatJSONL && value != formatCSV && value != formatTable {
+			return errors.New("output format must be jsonl, csv or table")
+		}
+		cfg.output = value
+	case "filter":
+		return cfg.pipeline.filter(value)
+	case "map":
+		return cfg.pipeline.mapping(value)
+	case "distinct":
+		return cfg.pipeline.distinct(value)
+	case "sort":
+		return cfg.pipeline.sort(value)
+	case "limit", "skip":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || n < 0 {
+			return errors.New("argument must be a non-negative integer")
+		}
+		if name == "limit" {
+			cfg.pipeline.limit(n)
+		} else {
+			cfg.pipeline.skip(n)
+		}
+	case "group-by":
+		return cfg.pipeline.groupBy(value)
+	case "agg":
+		return cfg.pipeline.aggregate(value)
+	default:
+		return errors.New("unknown flag")
+	}
+	return nil
+}
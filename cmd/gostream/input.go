@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/youthlin/stream/types"
+)
+
+const (
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+	formatTable = "table"
+)
+
+// readInputs 读取所有输入文件中的记录. 如果输入是 CSV, 同时返回表头作为列顺序
+func readInputs(cfg *config, stdin io.Reader) ([]types.T, []string, error) {
+	files := cfg.files
+	if len(files) == 0 {
+		files = []string{"-"}
+	}
+	var records []types.T
+	var columns []string
+	for _, file := range files {
+		format := cfg.input
+		if format == "" {
+			format = formatJSONL
+			if strings.EqualFold(filepath.Ext(file), ".csv") {
+				format = formatCSV
+			}
+		}
+		r, name, closeFn, err := open(file, stdin)
+		if err != nil {
+			return nil, nil, err
+		}
+		var header []string
+		if format == formatCSV {
+			records, header, err = readCSV(r, name, records)
+		} else {
+			records, err = readJSONL(r, name, records)
+		}
+		closeFn()
+		if err != nil {
+			return nil, nil, err
+		}
+		if columns == nil {
+			columns = header
+		}
+	}
+	return records, columns, nil
+}
+
+func open(file string, stdin io.Reader) (io.Reader, string, func(), error) {
+	if file == "-" {
+		return stdin, "<stdin>", func() {}, nil
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	return f, file, func() { _ = f.Close() }, nil
+}
+
+// readJSONL 每行一个 JSON 值, 空行会被忽略
+func readJSONL(r io.Reader, name string, records []types.T) ([]types.T, error) {
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if strings.TrimSpace(text) != "" {
+			v, e := decodeJSON(text)
+			if e != nil {
+				return nil, fmt.Errorf("%s:%d: %v", name, line, e)
+			}
+			records = append(records, v)
+		}
+		if err == io.EOF {
+			return records, nil
+		}
+	}
+}
+
+// decodeJSON 解码一个 JSON 值. 整数转为 int64, 以免大于 2^53 的整数经过 float64 丢失精度; 其他数字转为 float64
+func decodeJSON(text string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("invalid character after top-level value")
+	}
+	return fromJSONNumbers(v), nil
+}
+
+func fromJSONNumbers(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64() // 超出范围时为 ±Inf
+		return f
+	case map[string]interface{}:
+		for k, e := range x {
+			x[k] = fromJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range x {
+			x[i] = fromJSONNumbers(e)
+		}
+	}
+	return v
+}
+
+// readCSV 第一行是表头, 其余每行转为一个 map[string]interface{}
+func readCSV(r io.Reader, name string, records []types.T) ([]types.T, []string, error) {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err == io.EOF {
+		return records, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", name, err)
+	}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return records, header, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %v", name, err)
+		}
+		record := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			record[column] = parseCell(row[i])
+		}
+		records = append(records, record)
+	}
+}
+
+// parseCell 将单元格中的数字转为数字, 以便在表达式中比较和计算.
+// 形如 007 的文本转回字符串后会不同, 因此仍保留为字符串
+func parseCell(cell string) interface{} {
+	if i, err := strconv.ParseInt(cell, 10, 64); err == nil {
+		if strconv.FormatInt(i, 10) == cell {
+			return i
+		}
+		return cell
+	}
+	if cell != "" && strings.ContainsAny(cell[:1], "+-.0123456789") {
+		if f, err := strconv.ParseFloat(cell, 64); err == nil {
+			return f
+		}
+	}
+	return cell
+}
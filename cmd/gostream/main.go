@@ -0,0 +1,87 @@
+// Command gostream is a jq-like tool that processes JSON Lines or CSV records with Stream operations.
+//
+// Usage:
+//
+//	gostream [options] [pipeline flags] [file ...]
+//
+// Records are read from the files (or stdin if no file or the file is "-"),
+// every pipeline flag is applied in the order it appears, then the result is written to stdout.
+//
+// Options:
+//
+//	--input jsonl|csv          input format, default by file extension, or jsonl
+//	--output jsonl|csv|table   output format, default jsonl
+//
+// Pipeline flags:
+//
+//	--filter EXPR              keep records which EXPR is true
+//	--map EXPR                 replace each record with the value of EXPR
+//	--distinct KEY             remove records with duplicate KEY
+//	--sort KEY[:desc]          sort records by KEY stably
+//	--limit N                  keep at most N records
+//	--skip N                   drop the first N records
+//	--group-by KEY             group records by KEY, followed by an optional
+//	--agg count|sum:EXPR|avg:EXPR  aggregation of each group, default count
+//
+// EXPR and KEY are expressions of package github.com/youthlin/stream/expr, e.g.
+//
+//	gostream --filter "age >= 18 && country in ['CN','US']" --sort age:desc --limit 10 users.jsonl
+//	gostream --group-by country --agg avg:age --output table users.csv
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const usage = `usage: gostream [options] [pipeline flags] [file ...]
+
+options:
+  --input jsonl|csv              input format, default by file extension, or jsonl
+  --output jsonl|csv|table       output format, default jsonl
+
+pipeline flags, applied in order:
+  --filter EXPR                  keep records which EXPR is true
+  --map EXPR                     replace each record with the value of EXPR
+  --distinct KEY                 remove records with duplicate KEY
+  --sort KEY[:desc]              sort records by KEY stably
+  --limit N                      keep at most N records
+  --skip N                       drop the first N records
+  --group-by KEY                 group records by KEY, may be followed by
+  --agg count|sum:EXPR|avg:EXPR  aggregation of each group, default count
+`
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		if err != errHelp {
+			fmt.Fprintln(os.Stderr, "gostream:", err)
+		}
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// run 解析参数, 读取输入, 执行流水线, 输出结果
+func run(args []string, stdin io.Reader, stdout io.Writer) (err error) {
+	cfg, err := parseArgs(args)
+	if err != nil {
+		return err
+	}
+	records, columns, err := readInputs(cfg, stdin)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// 表达式求值出错时会 panic
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+				return
+			}
+			panic(r)
+		}
+	}()
+	s := cfg.pipeline.apply(records)
+	return write(cfg.output, s, cfg.pipeline.outputColumns(columns), stdout)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+const users = `{"name":"alice","age":20,"country":"CN"}
+{"name":"bob","age":15,"country":"US"}
+
+{"name":"carol","age":33,"country":"US"}
+{"name":"dave","age":40,"country":"JP"}
+{"name":"erin","age":20,"country":"CN"}
+`
+
+const orders = `id,customer,amount,zip
+1,alice,10.5,007
+2,bob,3,100
+3,alice,4.5,007
+`
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		input string
+		want  string
+	}{
+		{
+			name:  "filter map",
+			args:  []string{"--filter", "age >= 18 && country in ['CN','US']", "--map", "upper(name)"},
+			input: users,
+			want:  "\"ALICE\"\n\"CAROL\"\n\"ERIN\"\n",
+		},
+		{
+			name:  "operators in order",
+			args:  []string{"--sort=age", "--skip", "3", "--limit", "1", "--sort", "name:desc", "--output", "csv"},
+			input: users,
+			want:  "age,country,name\n33,US,carol\n",
+		},
+		{
+			name:  "distinct",
+			args:  []string{"--distinct", "age", "--map", "name"},
+			input: users,
+			want:  "\"alice\"\n\"bob\"\n\"carol\"\n\"dave\"\n",
+		},
+		{
+			name:  "group by",
+			args:  []string{"--group-by", "country", "--output", "table"},
+			input: users,
+			want:  "country  count\n-------  -----\nCN       2\nUS       2\nJP       1\n",
+		},
+		{
+			name:  "csv input",
+			args:  []string{"--input", "csv", "--group-by", "customer", "--agg", "sum:amount", "--output", "csv"},
+			input: orders,
+			want:  "customer,sum\nalice,15\nbob,3\n",
+		},
+		{
+			name:  "large integers keep precision",
+			args:  []string{"--filter", "id > 9007199254740992"},
+			input: "{\"id\":9007199254740993}\n{\"id\":9007199254740992,\"x\":[1.5,2]}\n",
+			want:  "{\"id\":9007199254740993}\n",
+		},
+		{
+			name:  "sort by expression with colon",
+			args:  []string{"--sort", "time < '12:00'", "--map", "id"},
+			input: "{\"id\":1,\"time\":\"13:30\"}\n{\"id\":2,\"time\":\"09:15\"}\n{\"id\":3,\"time\":\"12:30\"}\n",
+			want:  "1\n3\n2\n",
+		},
+		{
+			name:  "sort large integers",
+			args:  []string{"--sort", "id:desc", "--map", "id"},
+			input: "{\"id\":9007199254740992}\n{\"id\":9007199254740993}\n",
+			want:  "9007199254740993\n9007199254740992\n",
+		},
+		{
+			name:  "distinct by json",
+			args:  []string{"--distinct", "tags", "--map", "id"},
+			input: "{\"id\":1,\"tags\":{\"a\":1,\"b\":2}}\n{\"id\":2,\"tags\":{\"b\":2,\"a\":1}}\n{\"id\":3,\"tags\":{\"a\":\"1\",\"b\":2}}\n",
+			want:  "1\n3\n",
+		},
+		{
+			name:  "csv keeps header order",
+			args:  []string{"--input", "csv", "--filter", "zip == '007'", "--output", "csv"},
+			input: orders,
+			want:  "id,customer,amount,zip\n1,alice,10.5,007\n3,alice,4.5,007\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := run(tt.args, strings.NewReader(tt.input), &out); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunError(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--filter", "age >"}, "--filter age >: expr: 1:6: unexpected end of expression"},
+		{[]string{"--limit", "-1"}, "--limit -1: argument must be a non-negative integer"},
+		{[]string{"--agg", "count"}, "--agg count: must follow --group-by"},
+		{[]string{"--output", "xml"}, "--output xml: output format must be jsonl, csv or table"},
+		{[]string{"--filter", "age > 'x'"}, "expr: 1:5: operator > cannot compare number and string"},
+	}
+	for _, tt := range tests {
+		err := run(tt.args, strings.NewReader(users), &bytes.Buffer{})
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%v: got error %v, want %s", tt.args, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/youthlin/stream"
+	"github.com/youthlin/stream/types"
+)
+
+// valueColumn 记录不是对象时(例如 --map 的结果是数字), 在 csv/table 中使用的列名
+const valueColumn = "value"
+
+// write 以指定格式输出流中的记录. columns 是优先使用的列顺序, 可以为 nil
+func write(format string, s stream.Stream, columns []string, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	var err error
+	switch format {
+	case formatCSV:
+		err = writeCSV(s, columns, bw)
+	case formatTable:
+		err = writeTable(s, columns, bw)
+	default:
+		err = writeJSONL(s, bw)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+func writeJSONL(s stream.Stream, w io.Writer) (err error) {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	s.AllMatch(func(e types.T) bool {
+		err = enc.Encode(e)
+		return err == nil
+	})
+	return
+}
+
+// writeCSV 流式输出, 列由第一条记录决定
+func writeCSV(s stream.Stream, columns []string, w io.Writer) (err error) {
+	cw := csv.NewWriter(w)
+	var header []string
+	s.AllMatch(func(e types.T) bool {
+		if header == nil {
+			header = mergeColumns(columns, []types.T{e})
+			if err = cw.Write(header); err != nil {
+				return false
+			}
+		}
+		err = cw.Write(row(e, header))
+		return err == nil
+	})
+	if err != nil {
+		return err
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeTable 需要所有记录才能确定列宽, 列是所有记录中字段的并集
+func writeTable(s stream.Stream, columns []string, w io.Writer) error {
+	records := s.ToSlice()
+	header := mergeColumns(columns, records)
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	line := make([]string, len(header))
+	for i, h := range header {
+		line[i] = strings.Repeat("-", len(h))
+	}
+	fmt.Fprintln(tw, strings.Join(line, "\t"))
+	for _, e := range records {
+		fmt.Fprintln(tw, strings.Join(row(e, header), "\t"))
+	}
+	return tw.Flush()
+}
+
+// mergeColumns 优先使用 preferred 中出现在记录里的列, 其余列按名称排序追加在后面
+func mergeColumns(preferred []string, records []types.T) []string {
+	present := make(map[string]bool)
+	for _, e := range records {
+		if m, ok := e.(map[string]interface{}); ok {
+			for k := range m {
+				present[k] = true
+			}
+		} else {
+			present[valueColumn] = true
+		}
+	}
+	var columns []string
+	for _, c := range preferred {
+		if present[c] {
+			columns = append(columns, c)
+			delete(present, c)
+		}
+	}
+	var rest []string
+	for c := range present {
+		rest = append(rest, c)
+	}
+	sort.Strings(rest)
+	return append(columns, rest...)
+}
+
+func row(e types.T, header []string) []string {
+	m, ok := e.(map[string]interface{})
+	if !ok {
+		m = map[string]interface{}{valueColumn: e}
+	}
+	cells := make([]string, len(header))
+	for i, c := range header {
+		cells[i] = format(m[c])
+	}
+	return cells
+}
+
+func format(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	return string(marshal(v))
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/youthlin/stream"
+	"github.com/youthlin/stream/expr"
+	"github.com/youthlin/stream/types"
+)
+
+// step 流水线中的一个操作
+type step struct {
+	apply func(stream.Stream) stream.Stream
+	// columns 返回操作后的输出列, 为 nil 表示不改变列
+	columns func(in []string) []string
+}
+
+// pipeline 按命令行参数顺序记录的操作
+type pipeline struct {
+	steps []step
+	// group 最后一个 --group-by, 紧随其后的 --agg 会修改它
+	group *grouping
+}
+
+func (p *pipeline) add(s step) {
+	p.steps = append(p.steps, s)
+	p.group = nil
+}
+
+func (p *pipeline) apply(records []types.T) stream.Stream {
+	s := stream.Of(records...)
+	for _, st := range p.steps {
+		s = st.apply(s)
+	}
+	return s
+}
+
+// outputColumns 输入列经过各个操作后的输出列
+func (p *pipeline) outputColumns(input []string) []string {
+	columns := input
+	for _, st := range p.steps {
+		if st.columns != nil {
+			columns = st.columns(columns)
+		}
+	}
+	return columns
+}
+
+func (p *pipeline) filter(src string) error {
+	test, err := expr.Compile(src)
+	if err != nil {
+		return err
+	}
+	p.add(step{apply: func(s stream.Stream) stream.Stream {
+		return s.Filter(test)
+	}})
+	return nil
+}
+
+func (p *pipeline) mapping(src string) error {
+	f, err := expr.CompileFunc(src)
+	if err != nil {
+		return err
+	}
+	p.add(step{apply: func(s stream.Stream) stream.Stream {
+		return s.Map(f)
+	}, columns: func([]string) []string {
+		return nil // 结果的列未知, 输出时按字段名排序
+	}})
+	return nil
+}
+
+func (p *pipeline) distinct(key string) error {
+	f, err := expr.CompileFunc(key)
+	if err != nil {
+		return err
+	}
+	p.add(step{apply: func(s stream.Stream) stream.Stream {
+		// 以 key 的 JSON 文本(对象的字段有序)去重, 不同的 key 不会因哈希冲突被误删
+		seen := make(map[string]struct{})
+		return s.Filter(func(e types.T) bool {
+			k := string(marshal(f(e)))
+			if _, ok := seen[k]; ok {
+				return false
+			}
+			seen[k] = struct{}{}
+			return true
+		})
+	}})
+	return nil
+}
+
+func (p *pipeline) sort(key string) error {
+	// 只识别末尾的 :asc 或 :desc, 表达式中的 ':' (如字符串 'a:b' 中的) 不是排序方向
+	desc := strings.HasSuffix(key, ":desc")
+	if desc {
+		key = strings.TrimSuffix(key, ":desc")
+	} else {
+		key = strings.TrimSuffix(key, ":asc")
+	}
+	f, err := expr.CompileFunc(key)
+	if err != nil {
+		return err
+	}
+	var cmp types.Comparator = func(left, right types.T) int {
+		return compareValues(f(left), f(right))
+	}
+	if desc {
+		cmp = types.ReverseOrder(cmp)
+	}
+	p.add(step{apply: func(s stream.Stream) stream.Stream {
+		// 稳定排序, 多个 --sort 时相等的记录保持上一次排序的顺序
+		records := s.ToSlice()
+		sort.SliceStable(records, func(i, j int) bool {
+			return cmp(records[i], records[j]) < 0
+		})
+		return stream.Of(records...)
+	}})
+	return nil
+}
+
+func (p *pipeline) limit(n int64) {
+	p.add(step{apply: func(s stream.Stream) stream.Stream {
+		return s.Limit(n)
+	}})
+}
+
+func (p *pipeline) skip(n int64) {
+	p.add(step{apply: func(s stream.Stream) stream.Stream {
+		return s.Skip(n)
+	}})
+}
+
+func (p *pipeline) groupBy(key string) error {
+	f, err := expr.CompileFunc(key)
+	if err != nil {
+		return err
+	}
+	g := &grouping{name: key, key: f, agg: "count"}
+	p.add(step{apply: g.apply, columns: func([]string) []string {
+		return []string{g.name, g.agg}
+	}})
+	p.group = g
+	return nil
+}
+
+func (p *pipeline) aggregate(spec string) error {
+	if p.group == nil {
+		return errors.New("must follow --group-by")
+	}
+	name, arg := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		name, arg = spec[:i], spec[i+1:]
+	}
+	switch name {
+	case "count":
+		if arg != "" {
+			return errors.New("count takes no argument")
+		}
+	case "sum", "avg":
+		if arg == "" {
+			return fmt.Errorf("%s needs an expression, e.g. %s:price", name, name)
+		}
+		f, err := expr.CompileFunc(arg)
+		if err != nil {
+			return err
+		}
+		p.group.value = f
+	default:
+		return errors.New("aggregation must be count, sum:EXPR or avg:EXPR")
+	}
+	p.group.agg = name
+	p.group = nil // 每个 --group-by 只能有一个 --agg
+	return nil
+}
+
+// grouping 分组聚合. 分组按第一次出现的顺序输出
+type grouping struct {
+	name  string
+	key   types.Function
+	agg   string
+	value types.Function
+}
+
+type group struct {
+	key   types.R
+	count int64
+	sum   float64
+}
+
+func (g *grouping) apply(s stream.Stream) stream.Stream {
+	index := make(map[string]*group)
+	groups := s.ReduceWith([]*group{}, func(acc types.R, e types.T) types.R {
+		key := g.key(e)
+		id := string(marshal(key))
+		gr, ok := index[id]
+		if !ok {
+			gr = &group{key: key}
+			index[id] = gr
+			acc = append(acc.([]*group), gr)
+		}
+		gr.count++
+		if g.value != nil {
+			v, ok := toNumber(g.value(e))
+			if !ok {
+				panic(fmt.Errorf("%s: value of group %v is not a number", g.agg, key))
+			}
+			gr.sum += v
+		}
+		return acc
+	}).([]*group)
+	return stream.OfSlice(groups).Map(func(e types.T) types.R {
+		gr := e.(*group)
+		record := map[string]interface{}{g.name: gr.key}
+		switch g.agg {
+		case "count":
+			record[g.agg] = gr.count
+		case "sum":
+			record[g.agg] = gr.sum
+		case "avg":
+			record[g.agg] = gr.sum / float64(gr.count)
+		}
+		return record
+	})
+}
+
+func marshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return []byte(fmt.Sprintf("%#v", v))
+	}
+	return b
+}
+
+func toNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// compareValues 排序使用的比较: nil < bool < 数字 < 字符串 < 其他(按 JSON 文本)
+func compareValues(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return ra - rb
+	}
+	switch x := a.(type) {
+	case nil:
+		return 0
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		}
+		if !x {
+			return -1
+		}
+		return 1
+	case string:
+		return strings.Compare(x, b.(string))
+	}
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok { // 不转换为 float64, 超过 2^53 的整数也能正确比较
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	if x, ok := toNumber(a); ok {
+		y, _ := toNumber(b)
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(string(marshal(a)), string(marshal(b)))
+}
+
+func rank(v interface{}) int {
+	switch v.(type) {
+	case nil:
+		return 0
+	case bool:
+		return 1
+	case int64, float64:
+		return 2
+	case string:
+		return 3
+	}
+	return 4
+}
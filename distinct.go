@@ -0,0 +1,150 @@
+package stream
+
+import (
+	"container/list"
+	"math"
+	"time"
+
+	"github.com/youthlin/stream/clock"
+	"github.com/youthlin/stream/types"
+)
+
+// 内存有界的去重操作, 用于无限或很大的流
+
+// DistinctWindow 近似去重: 只记住最近出现的 maxKeys 个不同的标识(LRU), 重复出现会刷新标识,
+// 所以与最近 maxKeys 个不同元素重复的元素会被移除. maxKeys 不大于 0 时为 1
+// DistinctWindow removes an element if its key is one of the maxKeys most recently seen keys (LRU).
+func (s *stream) DistinctWindow(distincter types.IntFunction, maxKeys int) Stream {
+	if maxKeys < 1 {
+		maxKeys = 1
+	}
+	return newNode(s, func(down stage) stage {
+		var (
+			lru  *list.List // 最近出现的在前
+			keys map[int]*list.Element
+		)
+		return newChainedStage(down, begin(func(int64) {
+			lru, keys = list.New(), make(map[int]*list.Element)
+			down.Begin(unknownSize)
+		}), action(func(t types.T) {
+			hash := distincter(t)
+			if e, ok := keys[hash]; ok {
+				lru.MoveToFront(e)
+				return
+			}
+			keys[hash] = lru.PushFront(hash)
+			if lru.Len() > maxKeys {
+				delete(keys, lru.Remove(lru.Back()).(int))
+			}
+			down.Accept(t)
+		}), end(func() {
+			lru, keys = nil, nil
+			down.End()
+		}))
+	})
+}
+
+// DistinctApprox 使用布隆过滤器去重, 内存约为 expectedN*1.44*log2(1/fpRate) 位.
+// 不会漏掉重复元素, 但不重复的元素有一定概率被误判为重复而移除:
+// 不同元素不超过 expectedN 个时误判率不超过 fpRate, 超过后误判率逐渐升高.
+// expectedN 不大于 0 时为 1, fpRate 不在 (0, 1) 范围内时为 0.01
+// DistinctApprox removes duplicates by a Bloom filter, a distinct element is wrongly removed
+// with probability at most fpRate while there are no more than expectedN distinct keys.
+func (s *stream) DistinctApprox(distincter types.IntFunction, expectedN int, fpRate float64) Stream {
+	return newNode(s, func(down stage) stage {
+		var filter *bloomFilter
+		return newChainedStage(down, begin(func(int64) {
+			filter = newBloomFilter(expectedN, fpRate)
+			down.Begin(unknownSize)
+		}), action(func(t types.T) {
+			if filter.add(mix(uint64(distincter(t)))) {
+				down.Accept(t)
+			}
+		}), end(func() {
+			filter = nil
+			down.End()
+		}))
+	})
+}
+
+// DistinctWithin 按时间去重: 元素通过后, 相同标识的元素在 d 时间内会被移除, 之后可以再次通过.
+// 内存中只保存 d 时间内通过的元素的标识. 时间来自 clk, 真实时间使用 clock.System()
+// DistinctWithin removes an element if an element with the same key passed within d.
+func (s *stream) DistinctWithin(distincter types.IntFunction, d time.Duration, clk clock.Clock) Stream {
+	return newNode(s, func(down stage) stage {
+		type passed struct {
+			hash int
+			at   time.Time
+		}
+		var (
+			queue []passed // 按通过时间排序
+			keys  map[int]struct{}
+		)
+		return newChainedStage(down, begin(func(int64) {
+			queue, keys = nil, make(map[int]struct{})
+			down.Begin(unknownSize)
+		}), action(func(t types.T) {
+			now := clk.Now()
+			// 过期的标识一定在队首
+			i := 0
+			for ; i < len(queue) && now.Sub(queue[i].at) >= d; i++ {
+				delete(keys, queue[i].hash)
+			}
+			queue = queue[i:]
+			hash := distincter(t)
+			if _, ok := keys[hash]; ok {
+				return
+			}
+			keys[hash] = struct{}{}
+			queue = append(queue, passed{hash: hash, at: now})
+			down.Accept(t)
+		}), end(func() {
+			queue, keys = nil, nil
+			down.End()
+		}))
+	})
+}
+
+// bloomFilter 布隆过滤器, 使用一个哈希值的高低两部分组合出 k 个哈希函数
+type bloomFilter struct {
+	bits []uint64
+	m    uint64 // 位数
+	k    uint64 // 哈希函数个数
+}
+
+func newBloomFilter(expectedN int, fpRate float64) *bloomFilter {
+	if expectedN < 1 {
+		expectedN = 1
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		fpRate = 0.01
+	}
+	// 最优位数 m = -n*ln(p)/ln(2)^2, 哈希函数个数 k = m/n*ln(2)
+	m := uint64(math.Ceil(-float64(expectedN) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Max(1, math.Round(float64(m)/float64(expectedN)*math.Ln2)))
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// add 添加哈希值, 返回之前是否(可能)不存在
+func (b *bloomFilter) add(hash uint64) bool {
+	h1, h2 := hash&0xffffffff, hash>>32|1
+	added := false
+	for i := uint64(0); i < b.k; i++ {
+		bit := (h1 + i*h2) % b.m
+		if word, mask := bit/64, uint64(1)<<(bit%64); b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	}
+	return added
+}
+
+// mix splitmix64 的终结函数, 使相近的数哈希值无关
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
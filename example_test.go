@@ -1,12 +1,23 @@
 package stream_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"hash/crc32"
+	"math"
+	"math/rand"
 	"reflect"
 	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/youthlin/stream"
+	"github.com/youthlin/stream/clock"
+	"github.com/youthlin/stream/optional"
 	"github.com/youthlin/stream/types"
 )
 
@@ -537,3 +548,699 @@ func TestToMap(t *testing.T) {
 	// Output:
 	// map[0:0 1:10 2:20 3:30 4:40 5:50 6:60 7:70 8:80 9:90]
 }
+
+func ExamplePipeline() {
+	normalize := stream.NewPipeline().
+		Map(func(e types.T) types.R {
+			return strings.ToLower(strings.TrimSpace(e.(string)))
+		}).
+		Distinct(func(e types.T) int {
+			return int(crc32.ChecksumIEEE([]byte(e.(string))))
+		}).
+		Sorted(func(left, right types.T) int {
+			return strings.Compare(left.(string), right.(string))
+		})
+	fmt.Println(normalize.Apply(stream.Of(" b", "A ", "a", "c")).ToSlice())
+	fmt.Println(normalize.Apply(stream.OfStrings("Y", "x", " y")).ToSlice())
+	top2 := normalize.Limit(2)
+	fmt.Println(top2.Apply(stream.Of("d", "C", "b", "a")).ToSlice())
+	// Output:
+	// [a b c]
+	// [x y]
+	// [a b]
+}
+
+func ExampleOperator_Then() {
+	var double stream.Operator = func(s stream.Stream) stream.Stream {
+		return s.Map(func(e types.T) types.R {
+			return e.(int) * 2
+		})
+	}
+	var odd stream.Operator = func(s stream.Stream) stream.Stream {
+		return s.Filter(func(e types.T) bool {
+			return e.(int)%2 == 1
+		})
+	}
+	fmt.Println(odd.Then(double).Apply(stream.IntRange(0, 10)).ToSlice())
+	fmt.Println(double.Then(odd).Apply(stream.IntRange(0, 10)).ToSlice())
+	p := stream.NewPipeline(odd).Then(stream.NewPipeline(double, double).Apply)
+	fmt.Println(p.Apply(stream.IntRange(0, 5)).ToSlice())
+	// Output:
+	// [2 6 10 14 18]
+	// []
+	// [4 12]
+}
+
+func ExampleErrStreamConsumed() {
+	s := stream.Of(1, 2, 3)
+	evens := s.Filter(func(e types.T) bool {
+		return e.(int)%2 == 0
+	})
+	fmt.Println(evens.Count())
+	defer func() {
+		fmt.Println(recover() == stream.ErrStreamConsumed)
+	}()
+	s.Count() // s shares the same source with evens
+	// Output:
+	// 1
+	// true
+}
+
+func ExampleReplayable() {
+	s := stream.Replayable(stream.IntRange(0, 5))
+	fmt.Println(s.Count())
+	fmt.Println(s.ToSlice())
+	odd := s.Filter(func(e types.T) bool {
+		return e.(int)%2 == 1
+	})
+	fmt.Println(odd.ToSlice(), odd.Count())
+	// Output:
+	// 5
+	// [0 1 2 3 4]
+	// [1 3] 2
+}
+
+func ExampleStream_FlatMap_shortCircuit() {
+	s := stream.Of(1, 2).FlatMap(func(t types.T) stream.Stream {
+		return stream.Repeat(t) // infinite inner stream
+	}).Limit(3).ToSlice()
+	fmt.Println(s)
+	var visited int
+	first := stream.Of(1, 2).FlatMap(func(t types.T) stream.Stream {
+		return stream.IntRange(0, 1000).Peek(func(types.T) {
+			visited++
+		})
+	}).FindFirst().Get()
+	fmt.Println(first, visited)
+	// Output:
+	// [1 1 1]
+	// 0 1
+}
+
+func ExampleStream_FlatMapSlice() {
+	stream.OfStrings("a b", "c d e").
+		FlatMapSlice(func(t types.T) types.R {
+			return strings.Fields(t.(string))
+		}).
+		Limit(4).
+		ForEach(func(t types.T) {
+			fmt.Printf("%s,", t)
+		})
+	fmt.Println()
+	fmt.Println(stream.Of([]int{1, 2}, nil, []int{3}).Flatten().ToSlice())
+	// Output:
+	// a,b,c,d,
+	// [1 2 3]
+}
+
+func ExampleStream_ParallelReduce() {
+	add := func(acc types.R, e types.T) types.R {
+		return acc.(int) + e.(int)
+	}
+	combine := func(left, right types.T) types.T {
+		return left.(int) + right.(int)
+	}
+	squares := stream.OfInts(stream.IntRange(0, 1000).ToElementSlice(0).([]int)...).
+		Map(func(e types.T) types.R {
+			return e.(int) * e.(int)
+		})
+	fmt.Println(squares.ParallelReduce(0, add, combine, 4))
+	// not splittable: IntRange is not slice-backed, and Distinct is stateful
+	fmt.Println(stream.IntRange(0, 100).Distinct(func(e types.T) int {
+		return e.(int) % 10
+	}).ParallelReduce(0, add, combine, 3))
+	fmt.Println(stream.Of().ParallelReduce(0, add, combine, 3))
+	// Output:
+	// 332833500
+	// 45
+	// 0
+}
+
+func ExampleStream_ParallelReduce_float() {
+	floats := make([]float64, 100000)
+	for i := range floats {
+		floats[i] = 1 / float64(i+1)
+	}
+	sum := func() types.R {
+		return stream.OfFloat64s(floats...).ParallelReduce(0.0, func(acc types.R, e types.T) types.R {
+			return acc.(float64) + e.(float64)
+		}, func(left, right types.T) types.T {
+			return left.(float64) + right.(float64)
+		}, 8)
+	}
+	first := sum()
+	same := true
+	for i := 0; i < 20; i++ {
+		same = same && sum() == first
+	}
+	fmt.Printf("%.6f %v\n", first, same)
+	// Output:
+	// 12.090146 true
+}
+
+func ExampleStream_ParallelSorted() {
+	type pair struct{ key, seq int }
+	pairs := make([]types.T, 100000)
+	for i := range pairs {
+		pairs[i] = pair{key: (i * 7919) % 100, seq: i}
+	}
+	sorted := stream.Of(pairs...).ParallelSorted(func(left, right types.T) int {
+		return left.(pair).key - right.(pair).key
+	}, 6).ToSlice()
+	stable := true
+	for i := 1; i < len(sorted); i++ {
+		prev, cur := sorted[i-1].(pair), sorted[i].(pair)
+		if prev.key > cur.key || prev.key == cur.key && prev.seq > cur.seq {
+			stable = false
+		}
+	}
+	fmt.Println(len(sorted), stable, sorted[0], sorted[len(sorted)-1])
+	// small streams are sorted sequentially
+	fmt.Println(stream.Of(3, 1, 2).ParallelSorted(types.IntComparator, 4).ToSlice())
+	// Output:
+	// 100000 true {0 0} {99 99921}
+	// [1 2 3]
+}
+
+func ExampleStream_Buffered() {
+	var produced int32
+	first := stream.IntRange(0, 1000000).Peek(func(e types.T) {
+		atomic.AddInt32(&produced, 1)
+	}).Buffered(4).Map(func(e types.T) types.R {
+		return e.(int) * 10
+	}).Limit(3).ToSlice()
+	// the producer goroutine is stopped when Limit finishes
+	fmt.Println(first, atomic.LoadInt32(&produced) <= 3+4+1)
+	fmt.Println(stream.Of("a", "b", "c").Buffered(0).FindFirst().Get())
+	// Output:
+	// [0 10 20] true
+	// a
+}
+
+func TestBufferedPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recover() = %v, want boom", r)
+		}
+	}()
+	stream.IntRange(0, 10).Peek(func(e types.T) {
+		if e.(int) == 5 {
+			panic("boom")
+		}
+	}).Buffered(2).Count()
+	t.Error("expected panic")
+}
+
+func ExampleStream_RateLimit() {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	go func() {
+		for {
+			clk.BlockUntil(1) // a token is being waited for
+			clk.Advance(100 * time.Millisecond)
+		}
+	}()
+	// 10 per second, burst 2
+	stream.IntRange(0, 4).RateLimit(context.Background(), 10, 2, clk).ForEach(func(e types.T) {
+		fmt.Println(e, clk.Now().Sub(start))
+	})
+	// Output:
+	// 0 0s
+	// 1 0s
+	// 2 100ms
+	// 3 200ms
+}
+
+func ExampleStream_RateLimitBy() {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	go func() {
+		for {
+			clk.BlockUntil(1)
+			clk.Advance(time.Second)
+		}
+	}()
+	stream.Of("alice", "bob", "alice", "carol").RateLimitBy(context.Background(), func(e types.T) types.R {
+		return e
+	}, 1, 1, clk).ForEach(func(e types.T) {
+		fmt.Println(e, clk.Now().Sub(start))
+	})
+	// Output:
+	// alice 0s
+	// bob 0s
+	// alice 1s
+	// carol 1s
+}
+
+func TestRateLimitCancel(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		clk.BlockUntil(1) // waiting for the second token
+		cancel()
+	}()
+	got := stream.IntRange(0, 10).RateLimit(ctx, 1, 1, clk).ToSlice()
+	if !reflect.DeepEqual(got, []types.T{0}) {
+		t.Errorf("got %v, want [0]", got)
+	}
+}
+
+// visit is a request of key, which arrives after the clock is idle for a while.
+type visit struct {
+	key  string
+	idle time.Duration
+}
+
+func TestRateLimitByIdleCycles(t *testing.T) {
+	start := time.Now()
+	clk := clock.NewFake(start)
+	go func() {
+		for {
+			clk.BlockUntil(1)
+			clk.Advance(time.Second)
+		}
+	}()
+	// 1 per second, burst 2. In each cycle "hot" spends its burst and waits 1s for the third token,
+	// while new keys sweep the buckets: the empty "hot" bucket must be kept,
+	// and it must be released (or still full) after idling 10s.
+	const cycles = 5
+	var visits []types.T
+	for i := 0; i < cycles; i++ {
+		visits = append(visits, visit{key: "hot", idle: 10 * time.Second}, visit{key: "hot"})
+		for j := 0; j < 3; j++ {
+			visits = append(visits, visit{key: fmt.Sprintf("cold-%d-%d", i, j)})
+		}
+		visits = append(visits, visit{key: "hot"})
+	}
+	count := stream.Of(visits...).Peek(func(e types.T) {
+		clk.Advance(e.(visit).idle)
+	}).RateLimitBy(context.Background(), func(e types.T) types.R {
+		return e.(visit).key
+	}, 1, 2, clk).Count()
+	if count != int64(len(visits)) {
+		t.Errorf("count = %d, want %d", count, len(visits))
+	}
+	if got, want := clk.Now().Sub(start), cycles*11*time.Second; got != want {
+		t.Errorf("elapsed %v, want %v", got, want)
+	}
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	for _, c := range []struct {
+		rate  float64
+		burst int
+	}{{0, 1}, {-1, 1}, {math.NaN(), 1}, {1, 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RateLimit(%v, %d): expected panic", c.rate, c.burst)
+				}
+			}()
+			stream.Of(1).RateLimit(context.Background(), c.rate, c.burst, clock.NewFake(time.Now()))
+		}()
+	}
+}
+
+func ExampleStream_MapRetry() {
+	failures := map[types.T]int{"a": 2, "b": 5} // how many times each element fails
+	var slept []time.Duration
+	policy := stream.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		Sleep: func(d time.Duration) {
+			slept = append(slept, d)
+		},
+		DeadLetter: func(e types.T, err error) {
+			fmt.Println("dead letter:", e, err)
+		},
+	}
+	result := stream.Of("a", "b", "c").MapRetry(func(e types.T) (types.R, error) {
+		if failures[e] > 0 {
+			failures[e]--
+			return nil, errors.New("unavailable")
+		}
+		return strings.ToUpper(e.(string)), nil
+	}, policy).ToSlice()
+	fmt.Println(result, slept)
+	// Output:
+	// dead letter: b unavailable
+	// [A C] [100ms 200ms 100ms 200ms]
+}
+
+func ExampleStream_MapOrElse() {
+	fmt.Println(stream.Of("1", "x", "3").MapOrElse(func(e types.T) (types.R, error) {
+		return strconv.Atoi(e.(string))
+	}, func(e types.T, err error) types.R {
+		return -1
+	}).ToSlice())
+	// Output:
+	// [1 -1 3]
+}
+
+func TestMapRetryLargeAttempts(t *testing.T) {
+	// the backoff overflows float64 after about 1000 doublings, it must stay a valid positive duration
+	for _, initial := range []time.Duration{time.Second, 0} {
+		var slept []time.Duration
+		calls := 0
+		stream.Of(1).MapRetry(func(e types.T) (types.R, error) {
+			if calls++; calls < 2000 {
+				return nil, errors.New("unavailable")
+			}
+			return e, nil
+		}, stream.RetryPolicy{
+			MaxAttempts:    2000,
+			InitialBackoff: initial,
+			Sleep:          func(d time.Duration) { slept = append(slept, d) },
+		}).Count()
+		for i, d := range slept {
+			if d < 0 || i > 0 && d < slept[i-1] || initial == 0 && d != 0 {
+				t.Fatalf("initial %v: attempt %d slept %v", initial, i+1, d)
+			}
+		}
+	}
+}
+
+func TestMapRetryPanic(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	calls := 0
+	defer func() {
+		err, ok := recover().(*stream.RetryError)
+		if !ok || err.Attempts != 1 || !errors.Is(err, errPermanent) || calls != 1 {
+			t.Errorf("recover() = %v, calls = %d", err, calls)
+		}
+	}()
+	stream.Of(1).MapRetry(func(e types.T) (types.R, error) {
+		calls++
+		return nil, errPermanent
+	}, stream.RetryPolicy{
+		MaxAttempts: 5,
+		Sleep:       func(time.Duration) {},
+		IsRetryable: func(err error) bool { return err != errPermanent },
+	}).Count()
+	t.Error("expected panic")
+}
+
+func ExampleJoin() {
+	type customer struct {
+		id   int
+		name string
+	}
+	type order struct {
+		id       int
+		customer int
+	}
+	customers := stream.Replayable(stream.Of(customer{1, "alice"}, customer{2, "bob"}, customer{3, "carol"}))
+	orders := func() stream.Stream {
+		return stream.Of(order{100, 1}, order{101, 3}, order{102, 1}, order{103, 4})
+	}
+	orderCustomer := func(e types.T) types.R { return e.(order).customer }
+	customerID := func(e types.T) types.R { return e.(customer).id }
+
+	stream.Join(orders(), customers, orderCustomer, customerID).ForEach(func(e types.T) {
+		p := e.(types.Pair)
+		fmt.Println(p.First.(order).id, p.Second.(customer).name)
+	})
+	stream.LeftJoin(orders(), customers, orderCustomer, customerID).ForEach(func(e types.T) {
+		p := e.(types.Pair)
+		fmt.Println(p.First.(order).id, p.Second.(optional.Optional).Map(func(c types.T) types.R {
+			return c.(customer).name
+		}).OrElse("-"))
+	})
+	stream.FullOuterJoin(customers, orders(), customerID, orderCustomer).ForEach(func(e types.T) {
+		p := e.(types.Pair)
+		fmt.Println(p.First.(optional.Optional).IsPresent(), p.Second.(optional.Optional).IsPresent())
+	})
+	fmt.Println(stream.SemiJoin(customers, orders(), customerID, orderCustomer).ToSlice())
+	fmt.Println(stream.AntiJoin(customers, orders(), customerID, orderCustomer).ToSlice())
+	// Output:
+	// 100 alice
+	// 101 carol
+	// 102 alice
+	// 100 alice
+	// 101 carol
+	// 102 alice
+	// 103 -
+	// true true
+	// true true
+	// true true
+	// false true
+	// true false
+	// [{1 alice} {3 carol}]
+	// [{2 bob}]
+}
+
+func TestJoinReplayable(t *testing.T) {
+	key := func(e types.T) types.R { return e.(int) % 3 }
+	// the right side is consumed once, a Replayable join reuses it
+	for _, left := range []stream.Stream{stream.IntRange(0, 2), stream.IntRange(0, 10)} {
+		joined := stream.Replayable(stream.LeftJoin(left, stream.Of(0, 1, 3, 4), key, key))
+		first := joined.Count()
+		if second := joined.Count(); first != second {
+			t.Errorf("Count() = %d then %d", first, second)
+		}
+		semi := stream.Replayable(stream.SemiJoin(left, stream.Of(0, 1), key, key))
+		if first, second := semi.Count(), semi.Count(); first != second {
+			t.Errorf("SemiJoin Count() = %d then %d", first, second)
+		}
+	}
+	// the hash table is built on the smaller left side, the output follows the right order
+	got := stream.Join(stream.Of(1, 2), stream.Of(5, 4, 2, 1), key, key).Map(func(e types.T) types.R {
+		p := e.(types.Pair)
+		return fmt.Sprint(p.First, p.Second)
+	}).ToSlice()
+	if want := []types.T{"2 5", "1 4", "2 2", "1 1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Join() = %v, want %v", got, want)
+	}
+}
+
+func TestLeftJoinNilElement(t *testing.T) {
+	key := func(types.T) types.R { return 0 }
+	// an optional.Optional can not hold nil, a matched nil looks the same as no match
+	pair := stream.LeftJoin(stream.Of(1), stream.Of(nil), key, key).ToSlice()[0].(types.Pair)
+	if pair.Second.(optional.Optional).IsPresent() {
+		t.Errorf("matched nil: Second = %v, want empty", pair.Second)
+	}
+	// wrapped elements can be told apart
+	type wrapper struct{ value types.T }
+	wrapped := stream.Of(nil).Map(func(e types.T) types.R { return wrapper{e} })
+	pair = stream.LeftJoin(stream.Of(1), wrapped, key, key).ToSlice()[0].(types.Pair)
+	if second := pair.Second.(optional.Optional); !second.IsPresent() || second.Get().(wrapper).value != nil {
+		t.Errorf("matched wrapper: Second = %v, want present", second)
+	}
+}
+
+func TestSetReplayable(t *testing.T) {
+	key := func(e types.T) types.R { return e }
+	for name, op := range map[string]func(a, b stream.Stream, key types.Function) stream.Stream{
+		"Union":               stream.Union,
+		"Intersect":           stream.Intersect,
+		"Except":              stream.Except,
+		"SymmetricDifference": stream.SymmetricDifference,
+	} {
+		// b is consumed once, a Replayable result reuses it
+		s := stream.Replayable(op(stream.Of(1, 2, 3), stream.Of(2, 3, 4), key))
+		first, second := s.ToSlice(), s.ToSlice()
+		if !reflect.DeepEqual(first, second) {
+			t.Errorf("%s: %v then %v", name, first, second)
+		}
+	}
+}
+
+func ExampleSortedMergeJoin() {
+	left := stream.Of(1, 2, 2, 4, 5)
+	right := stream.Of("1a", "2a", "2b", "3a", "5a")
+	stream.SortedMergeJoin(left, right, func(e types.T) types.R {
+		return e
+	}, func(e types.T) types.R {
+		return int(e.(string)[0] - '0')
+	}, types.IntComparator).ForEach(func(e types.T) {
+		p := e.(types.Pair)
+		fmt.Println(p.First, p.Second)
+	})
+	// short-circuit stops both sides
+	fmt.Println(stream.SortedMergeJoin(stream.IntRange(0, 1000000), stream.IntRange(5, 1000000), func(e types.T) types.R {
+		return e
+	}, func(e types.T) types.R {
+		return e
+	}, types.IntComparator).FindFirst().Get())
+	// Output:
+	// 1 1a
+	// 2 2a
+	// 2 2b
+	// 2 2a
+	// 2 2b
+	// 5 5a
+	// {5 5}
+}
+
+func ExampleUnion() {
+	a := func() stream.Stream { return stream.Of("apple", "Banana", "cherry", "apple") }
+	b := func() stream.Stream { return stream.Of("banana", "date", "Cherry", "elderberry") }
+	lower := func(e types.T) types.R { return strings.ToLower(e.(string)) }
+	fmt.Println(stream.Union(a(), b(), lower).ToSlice())
+	fmt.Println(stream.Intersect(a(), b(), lower).ToSlice())
+	fmt.Println(stream.Except(a(), b(), lower).ToSlice())
+	fmt.Println(stream.SymmetricDifference(a(), b(), lower).ToSlice())
+	fmt.Println(stream.Union(a(), b(), lower).Limit(2).ToSlice())
+	// Output:
+	// [apple Banana cherry date elderberry]
+	// [Banana cherry]
+	// [apple]
+	// [apple date elderberry]
+	// [apple Banana]
+}
+
+func ExampleStream_Sample() {
+	// 个数未知的流
+	i := 0
+	generated := stream.Generate(func() types.T { i++; return i }).Limit(1000)
+	sample := generated.Sample(5, rand.New(rand.NewSource(1)))
+	fmt.Println(len(sample))
+	fmt.Println(stream.OfInts(1, 2, 3).Sample(5, rand.New(rand.NewSource(1))))
+
+	// 每个元素被选中的次数大致相同
+	counts := make([]int, 10)
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 10000; n++ {
+		for _, e := range stream.IntRange(0, 10).Sample(3, rng) {
+			counts[e.(int)]++
+		}
+	}
+	fmt.Println(stream.OfInts(counts...).AllMatch(func(e types.T) bool {
+		return e.(int) > 2800 && e.(int) < 3200
+	}))
+	// Output:
+	// 5
+	// [1 2 3]
+	// true
+}
+
+func ExampleStream_SampleFraction() {
+	count := stream.IntRange(0, 10000).SampleFraction(0.1, rand.New(rand.NewSource(1))).Count()
+	fmt.Println(count > 900 && count < 1100)
+	fmt.Println(stream.IntRange(0, 10).SampleFraction(0, nil).Count())
+	fmt.Println(stream.IntRange(0, 10).SampleFraction(1, nil).Count())
+	// Output:
+	// true
+	// 0
+	// 10
+}
+
+func TestSampleFractionParallelReduce(t *testing.T) {
+	// the rng is used sequentially, so there is no data race (go test -race) and the result follows the seed
+	ints := stream.IntRange(0, 10000).ToSlice() // a slice source can be split
+	sample := func() stream.Stream {
+		return stream.Of(ints...).SampleFraction(0.5, rand.New(rand.NewSource(1)))
+	}
+	sum := func(acc types.R, e types.T) types.R { return acc.(int) + e.(int) }
+	want := sample().ReduceWith(0, sum)
+	got := sample().ParallelReduce(0, sum, func(a, b types.T) types.T { return a.(int) + b.(int) }, 4)
+	if got != want {
+		t.Errorf("ParallelReduce() = %v, want %v", got, want)
+	}
+}
+
+func ExampleStream_Shuffled() {
+	shuffled := stream.IntRange(0, 10).Shuffled(rand.New(rand.NewSource(1))).ToSlice()
+	fmt.Println(len(shuffled))
+	fmt.Println(stream.OfSlice(shuffled).Sorted(types.IntComparator).ToSlice())
+	fmt.Println(stream.Of().Shuffled(nil).ToSlice())
+	// Output:
+	// 10
+	// [0 1 2 3 4 5 6 7 8 9]
+	// []
+}
+
+func ExampleStream_WeightedSample() {
+	// 权重为 0 的元素不会被选中, 权重越大越容易被选中
+	weight := func(e types.T) float64 { return float64(e.(int)) }
+	counts := make([]int, 4)
+	rng := rand.New(rand.NewSource(1))
+	for n := 0; n < 10000; n++ {
+		for _, e := range stream.IntRange(0, 4).WeightedSample(1, weight, rng) {
+			counts[e.(int)]++
+		}
+	}
+	fmt.Println(counts[0], counts[1] < counts[2], counts[2] < counts[3])
+	fmt.Println(len(stream.IntRange(0, 4).WeightedSample(5, weight, nil)))
+	// Output:
+	// 0 true true
+	// 3
+}
+
+func ExampleStream_DistinctWindow() {
+	id := func(e types.T) int { return e.(int) }
+	// 只记住最近 2 个不同的标识: 重复的 1 会刷新标识, 2 在 1, 3 之后再次出现时已被忘记
+	fmt.Println(stream.OfInts(1, 2, 1, 3, 1, 2).DistinctWindow(id, 2).ToSlice())
+	// Output:
+	// [1 2 3 2]
+}
+
+func ExampleStream_DistinctApprox() {
+	id := func(e types.T) int { return e.(int) % 1000 }
+	// 不会漏掉重复元素, 不同元素有不超过 1% 的概率被误判为重复
+	count := stream.IntRange(0, 2000).DistinctApprox(id, 1000, 0.01).Count()
+	fmt.Println(count >= 990 && count <= 1000)
+	// Output:
+	// true
+}
+
+type message struct {
+	id    int
+	delay time.Duration // 与上一条消息的间隔
+}
+
+func ExampleStream_DistinctWithin() {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	stream.Of(message{1, 0}, message{2, time.Second}, message{1, time.Second}, message{2, time.Second}, message{1, time.Second}).
+		Peek(func(e types.T) {
+			clk.Advance(e.(message).delay)
+		}).
+		DistinctWithin(func(e types.T) int { return e.(message).id }, 3*time.Second, clk).
+		ForEach(func(e types.T) {
+			fmt.Println(clk.Now().Sub(start), e.(message).id)
+		})
+	// Output:
+	// 0s 1
+	// 1s 2
+	// 4s 1
+}
+
+func ExampleStream_CountBy() {
+	words := stream.OfStrings("apple", "banana", "avocado", "cherry", "blueberry", "apricot")
+	counts := words.CountBy(func(e types.T) types.R { return e.(string)[:1] })
+	fmt.Println(counts["a"], counts["b"], counts["c"], counts["d"])
+	// Output:
+	// 3 2 1 0
+}
+
+func ExampleStream_MostCommon() {
+	words := func() stream.Stream {
+		return stream.OfStrings(strings.Fields("the cat and the dog and the bird saw a cat")...)
+	}
+	identity := func(e types.T) types.R { return e }
+	fmt.Println(words().MostCommon(identity, 3))
+	// 次数相同时先出现的在前
+	fmt.Println(words().MostCommon(identity, 5))
+	fmt.Println(words().MostCommon(identity, 0))
+	// Output:
+	// [{the 3} {cat 2} {and 2}]
+	// [{the 3} {cat 2} {and 2} {dog 1} {bird 1}]
+	// []
+}
+
+func ExampleStream_Histogram() {
+	latencies := stream.OfInts(3, 7, 12, 25, 40, 90, 150, 1000)
+	identity := func(e types.T) types.R { return e }
+	for _, b := range latencies.Histogram(identity, stream.ExponentialBuckets(10, 10, 3)) {
+		fmt.Println(b.Low, b.High, b.Count)
+	}
+	fmt.Println(stream.OfFloat64s(0.5, 1, 1.5, 2.5).Histogram(identity, stream.LinearBuckets(0, 1, 3)))
+	// Output:
+	// -Inf 10 2
+	// 10 100 4
+	// 100 1000 1
+	// 1000 +Inf 1
+	// [{-Inf 0 0} {0 1 1} {1 2 2} {2 +Inf 1}]
+}
@@ -0,0 +1,542 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// valueKind 编译期可推断的值类型, 无法推断时为 kindUnknown
+type valueKind int
+
+const (
+	kindUnknown valueKind = iota
+	kindAny               // 仅用于函数参数声明: 接受任意类型
+	kindNil
+	kindBool
+	kindNumber
+	kindString
+	kindList
+)
+
+func (k valueKind) String() string {
+	switch k {
+	case kindNil:
+		return "nil"
+	case kindBool:
+		return "bool"
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	case kindList:
+		return "list"
+	default:
+		return "any"
+	}
+}
+
+// accepts 作为参数声明时, 是否接受给定类型的实参
+func (k valueKind) accepts(actual valueKind) bool {
+	return k == kindAny || actual == kindUnknown || k == actual
+}
+
+func kindOf(v interface{}) valueKind {
+	switch v.(type) {
+	case nil:
+		return kindNil
+	case bool:
+		return kindBool
+	case int64, float64:
+		return kindNumber
+	case string:
+		return kindString
+	case []interface{}:
+		return kindList
+	}
+	return kindUnknown
+}
+
+// node 语法树节点. 节点编译后不可变, 因此可以在多个 goroutine 中并发求值
+type node interface {
+	pos() int
+	kind() valueKind
+	eval(env interface{}) (interface{}, error)
+}
+
+// region literalNode
+
+type literalNode struct {
+	at    int
+	value interface{}
+}
+
+func (n *literalNode) pos() int        { return n.at }
+func (n *literalNode) kind() valueKind { return kindOf(n.value) }
+
+func (n *literalNode) eval(interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+// endregion literalNode
+
+// region listNode
+
+type listNode struct {
+	at    int
+	elems []node
+}
+
+func (n *listNode) pos() int        { return n.at }
+func (n *listNode) kind() valueKind { return kindList }
+
+func (n *listNode) eval(env interface{}) (interface{}, error) {
+	list := make([]interface{}, 0, len(n.elems))
+	for _, elem := range n.elems {
+		v, err := elem.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// endregion listNode
+
+// region fieldNode
+
+// fieldNode 字段访问. target 为 nil 时表示从求值环境的根对象上取字段
+type fieldNode struct {
+	at     int
+	target node
+	name   string
+}
+
+func (n *fieldNode) pos() int        { return n.at }
+func (n *fieldNode) kind() valueKind { return kindUnknown }
+
+func (n *fieldNode) eval(env interface{}) (interface{}, error) {
+	obj := env
+	if n.target != nil {
+		v, err := n.target.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		obj = v
+	}
+	v, err := lookupField(obj, n.name)
+	if err != nil {
+		return nil, &Error{Offset: n.at, Msg: err.Error()}
+	}
+	return v, nil
+}
+
+// endregion fieldNode
+
+// region indexNode
+
+type indexNode struct {
+	at     int
+	target node
+	index  node
+}
+
+func (n *indexNode) pos() int        { return n.at }
+func (n *indexNode) kind() valueKind { return kindUnknown }
+
+func (n *indexNode) eval(env interface{}) (interface{}, error) {
+	target, err := n.target.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	v, err := lookupIndex(target, index)
+	if err != nil {
+		return nil, &Error{Offset: n.at, Msg: err.Error()}
+	}
+	return v, nil
+}
+
+// endregion indexNode
+
+// region callNode
+
+type callNode struct {
+	at   int
+	fn   *function
+	args []node
+}
+
+func (n *callNode) pos() int        { return n.at }
+func (n *callNode) kind() valueKind { return n.fn.result }
+
+func (n *callNode) eval(env interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, &Error{Offset: n.at, Msg: n.fn.name + ": " + err.Error()}
+	}
+	return normalize(v), nil
+}
+
+// endregion callNode
+
+// region unaryNode
+
+type unaryNode struct {
+	at      int
+	op      string
+	operand node
+}
+
+func (n *unaryNode) pos() int { return n.at }
+
+func (n *unaryNode) kind() valueKind {
+	if n.op == "!" {
+		return kindBool
+	}
+	return kindNumber
+}
+
+func (n *unaryNode) eval(env interface{}) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch x := v.(type) {
+	case bool:
+		if n.op == "!" {
+			return !x, nil
+		}
+	case int64:
+		if n.op == "-" {
+			if x == math.MinInt64 {
+				return nil, errorf(n.at, "integer overflow: -(%d)", x)
+			}
+			return -x, nil
+		}
+	case float64:
+		if n.op == "-" {
+			return -x, nil
+		}
+	}
+	return nil, errorf(n.at, "operator %s not defined on %s", n.op, describe(v))
+}
+
+// endregion unaryNode
+
+// region binaryNode
+
+type binaryNode struct {
+	at    int
+	op    string
+	left  node
+	right node
+}
+
+func (n *binaryNode) pos() int { return n.left.pos() }
+
+func (n *binaryNode) kind() valueKind {
+	switch n.op {
+	case "+":
+		if k := n.left.kind(); k == n.right.kind() {
+			return k
+		}
+		return kindUnknown
+	case "-", "*", "/", "%":
+		return kindNumber
+	default:
+		return kindBool
+	}
+}
+
+func (n *binaryNode) eval(env interface{}) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if n.op == "&&" || n.op == "||" {
+		return n.logical(env, left)
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		return n.compare(left, right)
+	case "in", "not in":
+		found, err := contains(right, left)
+		if err != nil {
+			return nil, errorf(n.at, "operator %s: %v", n.op, err)
+		}
+		return found == (n.op == "in"), nil
+	default:
+		return n.arithmetic(left, right)
+	}
+}
+
+// logical 短路求值
+func (n *binaryNode) logical(env interface{}, left interface{}) (interface{}, error) {
+	l, ok := left.(bool)
+	if !ok {
+		return nil, errorf(n.left.pos(), "operator %s requires bool operands, found %s", n.op, describe(left))
+	}
+	if (n.op == "&&" && !l) || (n.op == "||" && l) {
+		return l, nil
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	r, ok := right.(bool)
+	if !ok {
+		return nil, errorf(n.right.pos(), "operator %s requires bool operands, found %s", n.op, describe(right))
+	}
+	return r, nil
+}
+
+func (n *binaryNode) compare(left, right interface{}) (interface{}, error) {
+	var c int
+	switch l := left.(type) {
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, errorf(n.at, "mismatched types %s and %s for operator %s", describe(left), describe(right), n.op)
+		}
+		c = strings.Compare(l, r)
+	default:
+		var ok bool
+		if c, ok = compareNumbers(left, right); !ok {
+			return nil, errorf(n.at, "operator %s cannot compare %s and %s", n.op, describe(left), describe(right))
+		}
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+func (n *binaryNode) arithmetic(left, right interface{}) (interface{}, error) {
+	if l, ok := left.(string); ok && n.op == "+" {
+		if r, ok := right.(string); ok {
+			return l + r, nil
+		}
+	}
+	li, lInt := left.(int64)
+	ri, rInt := right.(int64)
+	if lInt && rInt {
+		// 整数运算溢出时报错, 而不是静默回绕
+		switch n.op {
+		case "+":
+			if sum := li + ri; (sum > li) == (ri > 0) {
+				return sum, nil
+			}
+		case "-":
+			if diff := li - ri; (diff < li) == (ri > 0) {
+				return diff, nil
+			}
+		case "*":
+			if product := li * ri; li == 0 || product/li == ri && !(li == -1 && ri == math.MinInt64) {
+				return product, nil
+			}
+		case "/", "%":
+			if ri == 0 {
+				return nil, errorf(n.at, "integer division by zero")
+			}
+			if n.op == "%" {
+				return li % ri, nil
+			}
+			if li != math.MinInt64 || ri != -1 {
+				return li / ri, nil
+			}
+		}
+		return nil, errorf(n.at, "integer overflow: %d %s %d", li, n.op, ri)
+	}
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if !lok || !rok {
+		return nil, errorf(n.at, "operator %s not defined on %s and %s", n.op, describe(left), describe(right))
+	}
+	switch n.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		return lf / rf, nil
+	default:
+		return math.Mod(lf, rf), nil
+	}
+}
+
+// endregion binaryNode
+
+// region value helpers
+
+// normalize 将求值环境中取到的值统一为表达式内部使用的类型:
+// 各种整数 -> int64, 浮点数 -> float64, 底层类型为 string/bool 的命名类型 -> string/bool.
+// 其他类型原样返回
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil, bool, int64, float64, string, []interface{}:
+		return v
+	case int:
+		return int64(x)
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return float64(u)
+		}
+		return int64(u)
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if rv.IsNil() {
+			return nil
+		}
+	}
+	return v
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	}
+	return 0, false
+}
+
+// compareNumbers 比较两个数字, 两者都是 int64 时不经过 float64 以免丢失精度
+func compareNumbers(left, right interface{}) (int, bool) {
+	if li, ok := left.(int64); ok {
+		if ri, ok := right.(int64); ok {
+			switch {
+			case li < ri:
+				return -1, true
+			case li > ri:
+				return 1, true
+			}
+			return 0, true
+		}
+	}
+	lf, lok := toFloat(left)
+	rf, rok := toFloat(right)
+	if !lok || !rok {
+		return 0, false
+	}
+	switch {
+	case lf < rf:
+		return -1, true
+	case lf > rf:
+		return 1, true
+	}
+	return 0, true
+}
+
+// equal 数字按值比较(1 == 1.0), 列表/数组逐个元素使用 equal 比较, 其余类型使用 reflect.DeepEqual
+func equal(left, right interface{}) bool {
+	if c, ok := compareNumbers(left, right); ok {
+		return c == 0
+	}
+	l, r := reflect.ValueOf(left), reflect.ValueOf(right)
+	if isList(l) && isList(r) {
+		if l.Len() != r.Len() {
+			return false
+		}
+		for i := 0; i < l.Len(); i++ {
+			if !equal(normalize(l.Index(i).Interface()), normalize(r.Index(i).Interface())) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func isList(v reflect.Value) bool {
+	return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+}
+
+// contains 实现 in 运算符: 列表/数组包含元素, map 包含键, 字符串包含子串
+func contains(container, elem interface{}) (bool, error) {
+	switch c := container.(type) {
+	case string:
+		s, ok := elem.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot search %s in string", describe(elem))
+		}
+		return strings.Contains(c, s), nil
+	case []interface{}:
+		for _, e := range c {
+			if equal(elem, e) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case nil:
+		return false, nil
+	}
+	rv := reflect.ValueOf(container)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if equal(elem, normalize(rv.Index(i).Interface())) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		key, ok := convertKey(elem, rv.Type().Key())
+		if !ok {
+			return false, nil
+		}
+		return rv.MapIndex(key).IsValid(), nil
+	}
+	return false, fmt.Errorf("%s is not a list, map or string", describe(container))
+}
+
+// describe 用于错误信息中描述一个值的类型
+func describe(v interface{}) string {
+	if k := kindOf(v); k != kindUnknown {
+		return k.String()
+	}
+	return reflect.TypeOf(v).String()
+}
+
+// endregion value helpers
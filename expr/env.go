@@ -0,0 +1,205 @@
+package expr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// fieldCache 缓存结构体类型的字段索引: reflect.Type -> map[name][]int
+var fieldCache sync.Map
+
+// lookupField 从结构体(或其指针)、键为字符串的 map 中取字段
+func lookupField(obj interface{}, name string) (interface{}, error) {
+	if m, ok := obj.(map[string]interface{}); ok {
+		return normalize(m[name]), nil
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("cannot get field %q of nil", name)
+	}
+	rv := reflect.ValueOf(obj)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("cannot get field %q of nil %s", name, rv.Type())
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("cannot get field %q of %s: key is not string", name, rv.Type())
+		}
+		v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, nil
+		}
+		return normalize(v.Interface()), nil
+	case reflect.Struct:
+		fields := structFields(rv.Type())
+		index, ok := fields[name]
+		if !ok {
+			index, ok = fields[strings.ToLower(name)]
+		}
+		if !ok {
+			return nil, fmt.Errorf("unknown field %q in %s", name, rv.Type())
+		}
+		v, err := fieldByIndex(rv, index)
+		if err != nil {
+			return nil, fmt.Errorf("cannot get field %q: %v", name, err)
+		}
+		return normalize(v.Interface()), nil
+	}
+	return nil, fmt.Errorf("cannot get field %q of %s", name, describe(obj))
+}
+
+// fieldByIndex 同 reflect.Value.FieldByIndex, 但嵌入的空指针返回错误而不是 panic
+func fieldByIndex(rv reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && rv.Kind() == reflect.Ptr {
+			if rv.IsNil() {
+				return reflect.Value{}, fmt.Errorf("nil embedded %s", rv.Type())
+			}
+			rv = rv.Elem()
+		}
+		rv = rv.Field(x)
+	}
+	return rv, nil
+}
+
+// structFields 返回结构体可导出字段的名称到索引的映射.
+// 名称包括字段名、全小写的字段名、expr 标签和 json 标签; 嵌入结构体的字段会被提升, 外层字段优先
+func structFields(typ reflect.Type) map[string][]int {
+	if cached, ok := fieldCache.Load(typ); ok {
+		return cached.(map[string][]int)
+	}
+	fields := make(map[string][]int)
+	collectFields(typ, nil, fields, map[reflect.Type]bool{})
+	cached, _ := fieldCache.LoadOrStore(typ, fields)
+	return cached.(map[string][]int)
+}
+
+func collectFields(typ reflect.Type, prefix []int, fields map[string][]int, visited map[reflect.Type]bool) {
+	if visited[typ] {
+		return
+	}
+	visited[typ] = true
+	var embedded []reflect.StructField
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		index := append(append([]int{}, prefix...), i)
+		if f.Anonymous {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				f.Index = index
+				embedded = append(embedded, f)
+			}
+		}
+		if f.PkgPath != "" { // 未导出
+			continue
+		}
+		for _, name := range fieldNames(f) {
+			if _, exists := fields[name]; !exists {
+				fields[name] = index
+			}
+		}
+	}
+	for _, f := range embedded {
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		collectFields(ft, f.Index, fields, visited)
+	}
+}
+
+func fieldNames(f reflect.StructField) []string {
+	names := []string{f.Name, strings.ToLower(f.Name)}
+	for _, key := range []string{"expr", "json"} {
+		tag := f.Tag.Get(key)
+		if i := strings.Index(tag, ","); i >= 0 {
+			tag = tag[:i]
+		}
+		if tag != "" && tag != "-" {
+			names = append(names, tag)
+		}
+	}
+	return names
+}
+
+// lookupIndex 实现 x[i]: 切片/数组按下标, map 按键
+func lookupIndex(target, index interface{}) (interface{}, error) {
+	if target == nil {
+		return nil, fmt.Errorf("cannot index nil")
+	}
+	if list, ok := target.([]interface{}); ok {
+		i, err := toIndex(index, len(list))
+		if err != nil {
+			return nil, err
+		}
+		return list[i], nil
+	}
+	rv := reflect.ValueOf(target)
+	for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		i, err := toIndex(index, rv.Len())
+		if err != nil {
+			return nil, err
+		}
+		return normalize(rv.Index(i).Interface()), nil
+	case reflect.Map:
+		key, ok := convertKey(index, rv.Type().Key())
+		if !ok {
+			return nil, fmt.Errorf("cannot use %s as key of %s", describe(index), rv.Type())
+		}
+		v := rv.MapIndex(key)
+		if !v.IsValid() {
+			return nil, nil
+		}
+		return normalize(v.Interface()), nil
+	}
+	return nil, fmt.Errorf("cannot index %s", describe(target))
+}
+
+func toIndex(index interface{}, length int) (int, error) {
+	i, ok := index.(int64)
+	if !ok {
+		return 0, fmt.Errorf("index must be integer, found %s", describe(index))
+	}
+	if i < 0 || i >= int64(length) {
+		return 0, fmt.Errorf("index %d out of range [0, %d)", i, length)
+	}
+	return int(i), nil
+}
+
+// convertKey 将表达式中的值转为 map 键的类型
+func convertKey(key interface{}, keyType reflect.Type) (reflect.Value, bool) {
+	if key == nil {
+		return reflect.Value{}, false
+	}
+	kv := reflect.ValueOf(key)
+	if kv.Type().AssignableTo(keyType) {
+		return kv, true
+	}
+	switch keyType.Kind() {
+	case reflect.String:
+		if s, ok := key.(string); ok {
+			return reflect.ValueOf(s).Convert(keyType), true
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if _, ok := toFloat(key); ok {
+			return kv.Convert(keyType), true
+		}
+	case reflect.Interface:
+		return kv, kv.Type().Implements(keyType)
+	}
+	return reflect.Value{}, false
+}
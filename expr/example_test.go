@@ -0,0 +1,178 @@
+package expr_test
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/youthlin/stream"
+	"github.com/youthlin/stream/expr"
+	"github.com/youthlin/stream/types"
+)
+
+type Address struct {
+	City string `json:"city"`
+}
+
+type User struct {
+	Name    string `json:"name"`
+	Age     int    `json:"age"`
+	Country string `expr:"country"`
+	Tags    []string
+	*Address
+}
+
+func ExampleCompile() {
+	adult, err := expr.Compile("age >= 18 && country in ['CN','US']")
+	if err != nil {
+		panic(err)
+	}
+	users := []User{
+		{Name: "Alice", Age: 20, Country: "CN"},
+		{Name: "Bob", Age: 16, Country: "US"},
+		{Name: "Carol", Age: 30, Country: "JP"},
+		{Name: "Dave", Age: 42, Country: "US"},
+	}
+	stream.OfSlice(users).Filter(adult).ForEach(func(e types.T) {
+		fmt.Println(e.(User).Name)
+	})
+	// Output:
+	// Alice
+	// Dave
+}
+
+func ExampleCompile_map() {
+	test := expr.MustCompile(`status == "active" and not (score < 60.5)`)
+	fmt.Println(test(map[string]interface{}{"status": "active", "score": 61}))
+	fmt.Println(test(map[string]interface{}{"status": "active", "score": 60}))
+	fmt.Println(test(map[string]interface{}{"score": 100}))
+	// Output:
+	// true
+	// false
+	// false
+}
+
+func ExampleCompile_error() {
+	for _, src := range []string{
+		"age >= ",
+		"age > 18 && 'yes'",
+		"upper(name, 1)",
+		"lower(1)",
+		"size(name)",
+		"name = 'x'",
+		"a < b < c",
+		"1 + 1",
+	} {
+		_, err := expr.Compile(src)
+		fmt.Println(err)
+	}
+	// Output:
+	// expr: 1:8: unexpected end of expression
+	// expr: 1:13: operator && requires bool operands, found string
+	// expr: 1:1: function upper expects 1 argument(s), found 2
+	// expr: 1:7: argument 1 of lower must be string, found number
+	// expr: 1:1: unknown function "size"
+	// expr: 1:6: unexpected character '='
+	// expr: 1:7: comparison operators cannot be chained, use && instead
+	// expr: 1:1: expression result is number, not bool
+}
+
+func ExampleCompileFunc() {
+	f := expr.MustCompileFunc("upper(name) + '@' + city")
+	fmt.Println(f(&User{Name: "alice", Address: &Address{City: "Beijing"}}))
+	stream.Of(
+		map[string]interface{}{"price": 2.5, "count": 4},
+		map[string]interface{}{"price": 10, "count": 3},
+	).Map(expr.MustCompileFunc("price * count")).ForEach(func(e types.T) {
+		fmt.Println(e)
+	})
+	// Output:
+	// ALICE@Beijing
+	// 10
+	// 30
+}
+
+func ExampleProgram_Eval() {
+	p, _ := expr.Parse("tags[1]")
+	_, err := p.Eval(User{Tags: []string{"a"}})
+	fmt.Println(err)
+	p, _ = expr.Parse("address.city")
+	_, err = p.Eval(User{})
+	fmt.Println(err)
+	p, _ = expr.Parse("len(tags) > 0 && tags[0] == 'vip'")
+	fmt.Println(p.Eval(User{Tags: []string{"vip"}}))
+	// Output:
+	// expr: 1:5: index 1 out of range [0, 1)
+	// expr: 1:9: cannot get field "city" of nil
+	// true <nil>
+}
+
+func TestConcurrentEval(t *testing.T) {
+	test := expr.MustCompile("n % 2 == 0 && name != ''")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				got := test(map[string]interface{}{"n": i + j, "name": "x"})
+				if got != ((i+j)%2 == 0) {
+					t.Errorf("n=%d got %v", i+j, got)
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestIntegerBounds(t *testing.T) {
+	for _, c := range []struct {
+		src  string
+		want interface{}
+		err  string
+	}{
+		{src: "-9223372036854775808", want: int64(math.MinInt64)},
+		{src: "-9223372036854775808 + 1", want: int64(math.MinInt64 + 1)},
+		{src: "1 - -5", want: int64(6)},
+		{src: "-9223372036854775809", err: "expr: 1:2: integer -9223372036854775809 out of range"},
+		{src: "9223372036854775807 + 1", err: "expr: 1:21: integer overflow: 9223372036854775807 + 1"},
+		{src: "-9223372036854775808 - 1", err: "expr: 1:22: integer overflow: -9223372036854775808 - 1"},
+		{src: "4611686018427387904 * 2", err: "expr: 1:21: integer overflow: 4611686018427387904 * 2"},
+		{src: "-1 * -9223372036854775808", err: "expr: 1:4: integer overflow: -1 * -9223372036854775808"},
+		{src: "-9223372036854775808 / -1", err: "expr: 1:22: integer overflow: -9223372036854775808 / -1"},
+		{src: "-(-9223372036854775808)", err: "expr: 1:1: integer overflow: -(-9223372036854775808)"},
+		{src: "-9223372036854775808 % -1", want: int64(0)},
+	} {
+		p, err := expr.Parse(c.src)
+		var got interface{}
+		if err == nil {
+			got, err = p.Eval(nil)
+		}
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%s: error %v, want %q", c.src, err, c.err)
+			}
+		} else if err != nil || got != c.want {
+			t.Errorf("%s = %v, %v; want %v", c.src, got, err, c.want)
+		}
+	}
+}
+
+func TestListEqual(t *testing.T) {
+	for src, want := range map[string]bool{
+		"[1, 2] == [1.0, 2.0]":                     true,
+		"[1, [2]] == [1.0, [2.0]]":                 true,
+		"[1, 2] == [1, 2, 3]":                      false,
+		"[1, 'a'] != [1, 'b']":                     true,
+		"tags == ['a', 'b']":                       true,
+		"nums == [1, 2.5]":                         true,
+		"[9007199254740993] == [9007199254740992]": false,
+	} {
+		got := expr.MustCompileFunc(src)(map[string]interface{}{"tags": []string{"a", "b"}, "nums": []float64{1, 2.5}})
+		if got != want {
+			t.Errorf("%s = %v, want %v", src, got, want)
+		}
+	}
+}
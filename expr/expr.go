@@ -0,0 +1,186 @@
+// Package expr is a small expression language used to configure stream operations
+// from text, such as filters written in YAML.
+// 一个小型表达式语言, 可以把文本形式的表达式编译为 types.Predicate / types.Function.
+//
+// An expression is evaluated against one element (the environment).
+// Identifiers are looked up as fields of the element, which may be a struct (or a pointer to it),
+// or a map with string keys such as map[string]interface{}.
+// A struct field matches by its Go name (case-insensitive), its `expr` tag or its `json` tag.
+// A missing map key evaluates to nil, a missing struct field is an error.
+//
+//	age >= 18 && country in ['CN', 'US']
+//	upper(name) + '@' + address.city
+//	tags[0] == "vip" || len(tags) > 3
+//
+// Supported operators, from lowest precedence to highest:
+//
+//	||  or
+//	&&  and
+//	==  !=  <  <=  >  >=  in  not in
+//	+  -
+//	*  /  %
+//	!  not  -
+//	.field  [index]  call(args)
+//
+// Literals are integers, floats, single or double quoted strings, true, false, nil and lists like [1, 2].
+// Integers of any Go type are compared and computed as int64, floats as float64;
+// integer overflow is an evaluation error. Numbers are equal by value (1 == 1.0), lists element by element.
+//
+// Compilation is strict: syntax errors, unknown functions, wrong argument counts
+// and type errors which can be detected without data are all reported with their position.
+// A compiled expression is immutable and can be used from multiple goroutines concurrently.
+//
+// The compiled functions accept interface{}, so they can also be used with the generic v2 package:
+//
+//	adult := expr.MustCompile("age >= 18")
+//	stream.Filter(users, func(u User) bool { return adult(u) })
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/youthlin/stream/types"
+)
+
+// Error is a compile or evaluate error, with the position where it happens.
+// 编译或求值错误, 包含出错位置
+type Error struct {
+	Expr   string // the source expression 表达式原文
+	Offset int    // byte offset in Expr 出错位置(字节偏移)
+	Msg    string
+}
+
+func (e *Error) Error() string {
+	line, col := e.Position()
+	return fmt.Sprintf("expr: %d:%d: %s", line, col, e.Msg)
+}
+
+// Position returns the 1-based line and column (in characters) of the error.
+// 返回从 1 开始计数的行号和列号
+func (e *Error) Position() (line, col int) {
+	prefix := e.Expr
+	if e.Offset < len(prefix) {
+		prefix = prefix[:e.Offset]
+	}
+	line = 1 + strings.Count(prefix, "\n")
+	if i := strings.LastIndex(prefix, "\n"); i >= 0 {
+		prefix = prefix[i+1:]
+	}
+	return line, 1 + utf8.RuneCountInString(prefix)
+}
+
+func newError(src string, offset int, format string, args ...interface{}) error {
+	return &Error{Expr: src, Offset: offset, Msg: fmt.Sprintf(format, args...)}
+}
+
+// errorf 求值时的错误, 表达式原文由 Program.Eval 补充
+func errorf(offset int, format string, args ...interface{}) error {
+	return newError("", offset, format, args...)
+}
+
+// Program is a compiled expression.
+// 编译后的表达式
+type Program struct {
+	src  string
+	root node
+}
+
+// Parse compiles the expression to a Program, which result can be any type.
+// 编译表达式, 结果可以是任意类型
+func Parse(src string) (*Program, error) {
+	root, err := parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Program{src: src, root: root}, nil
+}
+
+// String returns the source expression.
+func (p *Program) String() string {
+	return p.src
+}
+
+// Eval evaluates the expression against env.
+// 以 env 为求值环境计算表达式的值
+func (p *Program) Eval(env interface{}) (interface{}, error) {
+	v, err := p.root.eval(env)
+	if err != nil {
+		if e, ok := err.(*Error); ok && e.Expr == "" {
+			return nil, &Error{Expr: p.src, Offset: e.Offset, Msg: e.Msg}
+		}
+		return nil, err
+	}
+	return v, nil
+}
+
+// Test evaluates the expression as a condition. The result must be a bool.
+// 作为条件求值, 结果必须是 bool 类型
+func (p *Program) Test(env interface{}) (bool, error) {
+	v, err := p.Eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, &Error{Expr: p.src, Offset: p.root.pos(), Msg: "expression result is " + describe(v) + ", not bool"}
+	}
+	return b, nil
+}
+
+// Compile compiles a boolean expression to a types.Predicate,
+// which can be used in Stream.Filter, AllMatch etc.
+// The predicate panics with an *Error if evaluation fails.
+// 编译一个布尔表达式, 可用于 Filter 等操作. 求值出错时会 panic(*Error)
+func Compile(src string) (types.Predicate, error) {
+	p, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	if k := p.root.kind(); k != kindUnknown && k != kindBool {
+		return nil, newError(src, p.root.pos(), "expression result is %s, not bool", k)
+	}
+	return func(e types.T) bool {
+		ok, err := p.Test(e)
+		if err != nil {
+			panic(err)
+		}
+		return ok
+	}, nil
+}
+
+// CompileFunc compiles a expression to a types.Function, which can be used in Stream.Map.
+// The function panics with an *Error if evaluation fails.
+// 编译一个表达式, 可用于 Map 操作. 求值出错时会 panic(*Error)
+func CompileFunc(src string) (types.Function, error) {
+	p, err := Parse(src)
+	if err != nil {
+		return nil, err
+	}
+	return func(e types.T) types.R {
+		v, err := p.Eval(e)
+		if err != nil {
+			panic(err)
+		}
+		return v
+	}, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be compiled.
+func MustCompile(src string) types.Predicate {
+	test, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return test
+}
+
+// MustCompileFunc is like CompileFunc but panics if the expression cannot be compiled.
+func MustCompileFunc(src string) types.Function {
+	f, err := CompileFunc(src)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
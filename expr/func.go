@@ -0,0 +1,124 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// function 内置函数. args 声明每个参数的类型, 编译时检查参数个数和可推断的参数类型
+type function struct {
+	name   string
+	args   []valueKind
+	result valueKind
+	call   func(args []interface{}) (interface{}, error)
+}
+
+// functions 内置函数表
+var functions = map[string]*function{}
+
+func init() {
+	register("upper", []valueKind{kindString}, kindString, func(args []interface{}) (interface{}, error) {
+		s, err := stringArg(args, 0)
+		return strings.ToUpper(s), err
+	})
+	register("lower", []valueKind{kindString}, kindString, func(args []interface{}) (interface{}, error) {
+		s, err := stringArg(args, 0)
+		return strings.ToLower(s), err
+	})
+	register("trim", []valueKind{kindString}, kindString, func(args []interface{}) (interface{}, error) {
+		s, err := stringArg(args, 0)
+		return strings.TrimSpace(s), err
+	})
+	register("startsWith", []valueKind{kindString, kindString}, kindBool, func(args []interface{}) (interface{}, error) {
+		s, prefix, err := stringArgs(args)
+		return strings.HasPrefix(s, prefix), err
+	})
+	register("endsWith", []valueKind{kindString, kindString}, kindBool, func(args []interface{}) (interface{}, error) {
+		s, suffix, err := stringArgs(args)
+		return strings.HasSuffix(s, suffix), err
+	})
+	register("contains", []valueKind{kindAny, kindAny}, kindBool, func(args []interface{}) (interface{}, error) {
+		return contains(args[0], args[1])
+	})
+	register("len", []valueKind{kindAny}, kindNumber, func(args []interface{}) (interface{}, error) {
+		if s, ok := args[0].(string); ok {
+			return int64(len([]rune(s))), nil
+		}
+		if args[0] == nil {
+			return int64(0), nil
+		}
+		rv := reflect.ValueOf(args[0])
+		switch rv.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return int64(rv.Len()), nil
+		}
+		return nil, fmt.Errorf("cannot get length of %s", describe(args[0]))
+	})
+	register("abs", []valueKind{kindNumber}, kindNumber, func(args []interface{}) (interface{}, error) {
+		switch x := args[0].(type) {
+		case int64:
+			if x < 0 {
+				return -x, nil
+			}
+			return x, nil
+		case float64:
+			return math.Abs(x), nil
+		}
+		return nil, fmt.Errorf("argument must be number, found %s", describe(args[0]))
+	})
+	register("int", []valueKind{kindAny}, kindNumber, func(args []interface{}) (interface{}, error) {
+		switch x := args[0].(type) {
+		case int64:
+			return x, nil
+		case float64:
+			return int64(x), nil
+		case string:
+			return strconv.ParseInt(strings.TrimSpace(x), 10, 64)
+		case bool:
+			if x {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+		return nil, fmt.Errorf("cannot convert %s to int", describe(args[0]))
+	})
+	register("float", []valueKind{kindAny}, kindNumber, func(args []interface{}) (interface{}, error) {
+		if f, ok := toFloat(args[0]); ok {
+			return f, nil
+		}
+		if s, ok := args[0].(string); ok {
+			return strconv.ParseFloat(strings.TrimSpace(s), 64)
+		}
+		return nil, fmt.Errorf("cannot convert %s to float", describe(args[0]))
+	})
+	register("string", []valueKind{kindAny}, kindString, func(args []interface{}) (interface{}, error) {
+		if args[0] == nil {
+			return "", nil
+		}
+		return fmt.Sprint(args[0]), nil
+	})
+}
+
+func register(name string, args []valueKind, result valueKind, call func(args []interface{}) (interface{}, error)) {
+	functions[name] = &function{name: name, args: args, result: result, call: call}
+}
+
+func stringArg(args []interface{}, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %d must be string, found %s", i+1, describe(args[i]))
+	}
+	return s, nil
+}
+
+func stringArgs(args []interface{}) (string, string, error) {
+	s1, err := stringArg(args, 0)
+	if err != nil {
+		return "", "", err
+	}
+	s2, err := stringArg(args, 1)
+	return s1, s2, err
+}
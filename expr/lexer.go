@@ -0,0 +1,181 @@
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind 词法单元类型
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokInt
+	tokFloat
+	tokString
+	tokOp // 运算符及标点
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokEOF:
+		return "end of expression"
+	case tokIdent:
+		return "identifier"
+	case tokInt, tokFloat:
+		return "number"
+	case tokString:
+		return "string"
+	default:
+		return "operator"
+	}
+}
+
+// token 词法单元, pos 是从 0 开始的字节偏移
+type token struct {
+	kind tokenKind
+	text string // 原始文本; 字符串字面量时是解码后的内容
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return t.kind.String()
+	case tokString:
+		return fmt.Sprintf("string %q", t.text)
+	default:
+		return fmt.Sprintf("%s %q", t.kind, t.text)
+	}
+}
+
+// operators 按长度从长到短排列, 保证最长匹配
+var operators = []string{
+	"&&", "||", "==", "!=", "<=", ">=",
+	"!", "<", ">", "+", "-", "*", "/", "%",
+	"(", ")", "[", "]", ",", ".",
+}
+
+// lex 将表达式切分为词法单元
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '_' || unicode.IsLetter(r):
+			start := i
+			for i < len(src) {
+				r, size = utf8.DecodeRuneInString(src[i:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start})
+		case '0' <= r && r <= '9':
+			tok, next, err := lexNumber(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+		case r == '\'' || r == '"':
+			tok, next, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, tok)
+			i = next
+		default:
+			op := ""
+			for _, candidate := range operators {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, newError(src, i, "unexpected character %q", r)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src)}), nil
+}
+
+func lexNumber(src string, start int) (token, int, error) {
+	i := start
+	kind := tokInt
+	for i < len(src) && isDigit(src[i]) {
+		i++
+	}
+	if i+1 < len(src) && src[i] == '.' && isDigit(src[i+1]) {
+		kind = tokFloat
+		i++
+		for i < len(src) && isDigit(src[i]) {
+			i++
+		}
+	}
+	if i < len(src) && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if j < len(src) && (src[j] == '+' || src[j] == '-') {
+			j++
+		}
+		if j >= len(src) || !isDigit(src[j]) {
+			return token{}, 0, newError(src, i, "malformed exponent in number %q", src[start:j])
+		}
+		kind = tokFloat
+		for i = j; i < len(src) && isDigit(src[i]); i++ {
+		}
+	}
+	if i < len(src) && (src[i] == '_' || isLetter(src[i])) {
+		return token{}, 0, newError(src, i, "unexpected character %q after number", src[i])
+	}
+	return token{kind: kind, text: src[start:i], pos: start}, i, nil
+}
+
+func lexString(src string, start int) (token, int, error) {
+	quote := src[start]
+	var sb strings.Builder
+	for i := start + 1; i < len(src); i++ {
+		c := src[i]
+		switch c {
+		case quote:
+			return token{kind: tokString, text: sb.String(), pos: start}, i + 1, nil
+		case '\\':
+			if i+1 >= len(src) {
+				return token{}, 0, newError(src, start, "unterminated string")
+			}
+			i++
+			switch src[i] {
+			case 'n':
+				sb.WriteByte('\n')
+			case 't':
+				sb.WriteByte('\t')
+			case 'r':
+				sb.WriteByte('\r')
+			case '\\', '\'', '"':
+				sb.WriteByte(src[i])
+			default:
+				return token{}, 0, newError(src, i-1, "unknown escape sequence \\%c", src[i])
+			}
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return token{}, 0, newError(src, start, "unterminated string")
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
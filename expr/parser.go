@@ -0,0 +1,374 @@
+package expr
+
+import (
+	"strconv"
+)
+
+// parser 递归下降语法分析器, 优先级从低到高:
+//
+//	||  or
+//	&&  and
+//	==  !=  <  <=  >  >=  in  not in
+//	+  -
+//	*  /  %
+//	!  not  -(负号)
+//	.field  [index]  func(args)
+type parser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+func parse(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.errorf(tok, "unexpected %s", tok)
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// is 判断当前词法单元是否是给定的运算符或关键字
+func (p *parser) is(texts ...string) bool {
+	tok := p.peek()
+	if tok.kind != tokOp && tok.kind != tokIdent {
+		return false
+	}
+	for _, text := range texts {
+		if tok.text == text {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(text string) (token, error) {
+	tok := p.next()
+	if tok.kind != tokOp || tok.text != text {
+		return tok, p.errorf(tok, "expected %q, found %s", text, tok)
+	}
+	return tok, nil
+}
+
+func (p *parser) errorf(tok token, format string, args ...interface{}) error {
+	return newError(p.src, tok.pos, format, args...)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("||", "or") {
+		op := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.logical(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("&&", "and") {
+		op := p.next()
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.logical(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) logical(op token, left, right node) (node, error) {
+	for _, operand := range []node{left, right} {
+		if k := operand.kind(); k != kindUnknown && k != kindBool {
+			return nil, newError(p.src, operand.pos(), "operator %s requires bool operands, found %s", op.text, k)
+		}
+	}
+	name := "&&"
+	if op.text == "||" || op.text == "or" {
+		name = "||"
+	}
+	return &binaryNode{op: name, at: op.pos, left: left, right: right}, nil
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if !p.is("==", "!=", "<", "<=", ">", ">=", "in", "not") {
+		return left, nil
+	}
+	op := p.next()
+	name := op.text
+	if name == "not" {
+		if tok := p.peek(); tok.kind != tokIdent || tok.text != "in" {
+			return nil, p.errorf(tok, "expected \"in\" after \"not\", found %s", tok)
+		}
+		p.next()
+		name = "not in"
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	lk, rk := left.kind(), right.kind()
+	switch name {
+	case "<", "<=", ">", ">=":
+		for _, operand := range []node{left, right} {
+			if k := operand.kind(); k != kindUnknown && k != kindNumber && k != kindString {
+				return nil, newError(p.src, operand.pos(), "operator %s cannot compare %s", name, k)
+			}
+		}
+		if lk != kindUnknown && rk != kindUnknown && lk != rk {
+			return nil, p.errorf(op, "mismatched types %s and %s for operator %s", lk, rk, name)
+		}
+	case "in", "not in":
+		if rk != kindUnknown && rk != kindList && rk != kindString {
+			return nil, newError(p.src, right.pos(), "operator %s requires a list, map or string on the right, found %s", name, rk)
+		}
+		if rk == kindString && lk != kindUnknown && lk != kindString {
+			return nil, newError(p.src, left.pos(), "operator %s on a string requires a string on the left, found %s", name, lk)
+		}
+	}
+	if p.is("==", "!=", "<", "<=", ">", ">=", "in", "not") {
+		return nil, p.errorf(p.peek(), "comparison operators cannot be chained, use && instead")
+	}
+	return &binaryNode{op: name, at: op.pos, left: left, right: right}, nil
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("+", "-") {
+		op := p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.is("*", "/", "%") {
+		op := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if left, err = p.arithmetic(op, left, right); err != nil {
+			return nil, err
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) arithmetic(op token, left, right node) (node, error) {
+	lk, rk := left.kind(), right.kind()
+	for _, operand := range []node{left, right} {
+		k := operand.kind()
+		if k == kindUnknown || k == kindNumber || (op.text == "+" && k == kindString) {
+			continue
+		}
+		return nil, newError(p.src, operand.pos(), "operator %s not defined on %s", op.text, k)
+	}
+	if lk != kindUnknown && rk != kindUnknown && lk != rk {
+		return nil, p.errorf(op, "mismatched types %s and %s for operator %s", lk, rk, op.text)
+	}
+	return &binaryNode{op: op.text, at: op.pos, left: left, right: right}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if !p.is("!", "not", "-") {
+		return p.parsePostfix()
+	}
+	op := p.next()
+	if tok := p.peek(); op.text == "-" && tok.kind == tokInt {
+		// 负号与整数字面量一起解析, 否则 -9223372036854775808 中的 9223372036854775808 会超出范围
+		p.next()
+		v, err := strconv.ParseInt("-"+tok.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "integer -%s out of range", tok.text)
+		}
+		return &literalNode{at: op.pos, value: v}, nil
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	name, want := "!", kindBool
+	if op.text == "-" {
+		name, want = "-", kindNumber
+	}
+	if k := operand.kind(); k != kindUnknown && k != want {
+		return nil, newError(p.src, operand.pos(), "operator %s not defined on %s", op.text, k)
+	}
+	return &unaryNode{op: name, at: op.pos, operand: operand}, nil
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	n, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.is("."):
+			p.next()
+			tok := p.next()
+			if tok.kind != tokIdent {
+				return nil, p.errorf(tok, "expected field name after \".\", found %s", tok)
+			}
+			n = &fieldNode{at: tok.pos, target: n, name: tok.text}
+		case p.is("["):
+			open := p.next()
+			index, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			n = &indexNode{at: open.pos, target: n, index: index}
+		default:
+			return n, nil
+		}
+	}
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokInt:
+		v, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "integer %s out of range", tok.text)
+		}
+		return &literalNode{at: tok.pos, value: v}, nil
+	case tokFloat:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return nil, p.errorf(tok, "float %s out of range", tok.text)
+		}
+		return &literalNode{at: tok.pos, value: v}, nil
+	case tokString:
+		return &literalNode{at: tok.pos, value: tok.text}, nil
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return &literalNode{at: tok.pos, value: tok.text == "true"}, nil
+		case "nil", "null":
+			return &literalNode{at: tok.pos}, nil
+		case "and", "or", "not", "in":
+			return nil, p.errorf(tok, "unexpected keyword %q", tok.text)
+		}
+		if p.is("(") {
+			return p.parseCall(tok)
+		}
+		return &fieldNode{at: tok.pos, name: tok.text}, nil
+	case tokOp:
+		switch tok.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if _, err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			elems, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{at: tok.pos, elems: elems}, nil
+		}
+	}
+	return nil, p.errorf(tok, "unexpected %s", tok)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %q", name.text)
+	}
+	p.next() // (
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) != len(fn.args) {
+		return nil, p.errorf(name, "function %s expects %d argument(s), found %d", fn.name, len(fn.args), len(args))
+	}
+	for i, arg := range args {
+		if k := arg.kind(); !fn.args[i].accepts(k) {
+			return nil, newError(p.src, arg.pos(), "argument %d of %s must be %s, found %s", i+1, fn.name, fn.args[i], k)
+		}
+	}
+	return &callNode{at: name.pos, fn: fn, args: args}, nil
+}
+
+// parseList 解析以逗号分隔的表达式列表, 直到遇到 closing
+func (p *parser) parseList(closing string) ([]node, error) {
+	var elems []node
+	if p.is(closing) {
+		p.next()
+		return elems, nil
+	}
+	for {
+		elem, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+		if p.is(closing) {
+			p.next()
+			return elems, nil
+		}
+		if _, err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
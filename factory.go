@@ -11,7 +11,12 @@ import (
 var (
 	// ErrNotSlice a error to panic when call Slice but argument is not slice
 	ErrNotSlice = errors.New("not slice")
-	ErrNotMap   = errors.New("not map")
+	// ErrNotMap a error to panic when call Entries or OfMap but argument is not map
+	ErrNotMap = errors.New("not map")
+	// ErrStreamConsumed a error to panic when call a terminal operate on a stream which has been consumed.
+	// 流已经被消费过了, 再次执行终止操作时 panic 此错误. 如需多次消费可以使用 Replayable
+	ErrStreamConsumed = errors.New("stream has already been consumed: " +
+		"a stream can be operated by only one terminal operate, use Replayable if needed")
 )
 
 // Slice 把任意的切片类型转为[]T类型. 可用作 Of() 入参.
@@ -51,52 +56,64 @@ func Entries(mapValue types.T) []types.Pair {
 // Of create a Stream from some element
 // It's recommend to pass pointer type cause the element may be copy at each operate
 func Of(elements ...types.T) Stream {
-	return newHead(it(elements...))
+	return newHead(func() iterator {
+		return it(elements...)
+	})
 }
 
 func OfInts(element ...int) Stream {
-	return newHead(&intsIt{
-		base: &base{
-			current: 0,
-			size:    len(element),
-		},
-		elements: element,
+	return newHead(func() iterator {
+		return &intsIt{
+			base: &base{
+				current: 0,
+				size:    len(element),
+			},
+			elements: element,
+		}
 	})
 }
 func OfInt64s(element ...int64) Stream {
-	return newHead(&int64sIt{
-		base: &base{
-			current: 0,
-			size:    len(element),
-		},
-		elements: element,
+	return newHead(func() iterator {
+		return &int64sIt{
+			base: &base{
+				current: 0,
+				size:    len(element),
+			},
+			elements: element,
+		}
 	})
 }
 func OfFloat32s(element ...float32) Stream {
-	return newHead(&float32sIt{
-		base: &base{
-			current: 0,
-			size:    len(element),
-		},
-		elements: element,
+	return newHead(func() iterator {
+		return &float32sIt{
+			base: &base{
+				current: 0,
+				size:    len(element),
+			},
+			elements: element,
+		}
 	})
 }
 func OfFloat64s(element ...float64) Stream {
-	return newHead(&float64sIt{
-		base: &base{
-			current: 0,
-			size:    len(element),
-		},
-		elements: element,
+	return newHead(func() iterator {
+		return &float64sIt{
+			base: &base{
+				current: 0,
+				size:    len(element),
+			},
+			elements: element,
+		}
 	})
 }
 func OfStrings(element ...string) Stream {
-	return newHead(&stringIt{
-		base: &base{
-			current: 0,
-			size:    len(element),
-		},
-		elements: element,
+	return newHead(func() iterator {
+		return &stringIt{
+			base: &base{
+				current: 0,
+				size:    len(element),
+			},
+			elements: element,
+		}
 	})
 }
 
@@ -110,14 +127,15 @@ func OfSlice(slice types.T) Stream {
 		panic(ErrNotSlice)
 	}
 	value := reflect.ValueOf(slice)
-	it := &sliceIt{
-		base: &base{
-			current: 0,
-			size:    value.Len(),
-		},
-		sliceValue: value,
-	}
-	return newHead(it)
+	return newHead(func() iterator {
+		return &sliceIt{
+			base: &base{
+				current: 0,
+				size:    value.Len(),
+			},
+			sliceValue: value,
+		}
+	})
 }
 
 // OfMap return a Stream which element type is types.Pair.
@@ -131,31 +149,38 @@ func OfMap(mapValue types.T) Stream {
 		panic(ErrNotMap)
 	}
 	value := reflect.ValueOf(mapValue)
-	it := &mapIt{
-		base: &base{
-			current: 0,
-			size:    value.Len(),
-		},
-		mapValue: value.MapRange(),
-	}
-	return newHead(it)
+	return newHead(func() iterator {
+		return &mapIt{
+			base: &base{
+				current: 0,
+				size:    value.Len(),
+			},
+			mapValue: value.MapRange(),
+		}
+	})
 }
 
 // Iterate create a Stream by a seed and an UnaryOperator
 func Iterate(seed types.T, operator types.UnaryOperator) Stream {
-	return newHead(withSeed(seed, operator))
+	return newHead(func() iterator {
+		return withSeed(seed, operator)
+	})
 }
 
 // Generate generates a infinite Stream which each element is generate by Supplier
 func Generate(get types.Supplier) Stream {
-	return newHead(withSupplier(get))
+	return newHead(func() iterator {
+		return withSupplier(get)
+	})
 }
 
 // Repeat returns a infinite Stream which all element is same
 func Repeat(e types.T) Stream {
-	return newHead(withSupplier(func() types.T {
-		return e
-	}))
+	return newHead(func() iterator {
+		return withSupplier(func() types.T {
+			return e
+		})
+	})
 }
 
 // RepeatN returns a Stream which has `count` element and all the element is the given `e`
@@ -170,7 +195,9 @@ func IntRange(fromInclude, toExclude int) Stream {
 
 // IntRangeStep creates a Stream which element is the given range by step
 func IntRangeStep(fromInclude, toExclude, step int) Stream {
-	return newHead(withRange(epInt(fromInclude), epInt(toExclude), step)).Map(func(t types.T) types.R {
+	return newHead(func() iterator {
+		return withRange(epInt(fromInclude), epInt(toExclude), step)
+	}).Map(func(t types.T) types.R {
 		// streams.epInt is not int
 		// 所以转回 int 让调用方不至于迷惑
 		return int(t.(epInt))
@@ -184,7 +211,9 @@ func Int64Range(fromInclude, toExclude int64) Stream {
 
 // Int64RangeStep like IntRangeStep
 func Int64RangeStep(fromInclude, toExclude int64, step int) Stream {
-	return newHead(withRange(epInt64(fromInclude), epInt64(toExclude), step)).Map(func(t types.T) types.R {
+	return newHead(func() iterator {
+		return withRange(epInt64(fromInclude), epInt64(toExclude), step)
+	}).Map(func(t types.T) types.R {
 		return int64(t.(epInt64))
 	})
 }
@@ -0,0 +1,158 @@
+package stream
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/youthlin/stream/types"
+)
+
+// CountBy 按 key 统计元素个数, key 必须是可比较的
+// CountBy counts the elements by key, the key must be comparable.
+func (s *stream) CountBy(key types.Function) map[types.R]int64 {
+	return s.ReduceWith(make(map[types.R]int64), func(acc types.R, t types.T) types.R {
+		acc.(map[types.R]int64)[key(t)]++
+		return acc
+	}).(map[types.R]int64)
+}
+
+// MostCommon 出现次数最多的 n 个 key 及其次数(types.Pair{First: key, Second: int64}), 按次数降序排列,
+// 次数相同时先出现的 key 在前. 使用大小为 n 的堆选出前 n 个, key 必须是可比较的
+// MostCommon returns the n most common keys with their counts, sorted by count descending,
+// keys with the same count are in the encounter order.
+func (s *stream) MostCommon(key types.Function, n int) []types.Pair {
+	type entry struct {
+		count int64
+		order int // 第一次出现的顺序
+	}
+	counts := s.ReduceWith(make(map[types.R]*entry), func(acc types.R, t types.T) types.R {
+		m := acc.(map[types.R]*entry)
+		k := key(t)
+		if e, ok := m[k]; ok {
+			e.count++
+		} else {
+			m[k] = &entry{count: 1, order: len(m)}
+		}
+		return acc
+	}).(map[types.R]*entry)
+	h := &commonHeap{}
+	for k, e := range counts {
+		if n <= 0 {
+			break
+		}
+		c := common{key: k, count: e.count, order: e.order}
+		if h.Len() < n {
+			heap.Push(h, c)
+		} else if h.less((*h)[0], c) {
+			(*h)[0] = c
+			heap.Fix(h, 0)
+		}
+	}
+	result := make([]types.Pair, h.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		c := heap.Pop(h).(common)
+		result[i] = types.Pair{First: c.key, Second: c.count}
+	}
+	return result
+}
+
+type common struct {
+	key   types.R
+	count int64
+	order int
+}
+
+// commonHeap 最小堆, 堆顶是次数最少(次数相同时最后出现)的 key
+type commonHeap []common
+
+// less a 排在 b 之后
+func (h commonHeap) less(a, b common) bool {
+	if a.count != b.count {
+		return a.count < b.count
+	}
+	return a.order > b.order
+}
+func (h commonHeap) Len() int            { return len(h) }
+func (h commonHeap) Less(i, j int) bool  { return h.less(h[i], h[j]) }
+func (h commonHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *commonHeap) Push(x interface{}) { *h = append(*h, x.(common)) }
+func (h *commonHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Bucket 直方图的桶 [Low, High) 及其中值的个数
+// Bucket is a histogram bucket [Low, High) and the number of values in it.
+type Bucket struct {
+	Low   float64
+	High  float64
+	Count int64
+}
+
+// LinearBuckets 返回 count 个等差的边界 start, start+width, ...
+// LinearBuckets returns count boundaries, starting at start, each width apart.
+func LinearBuckets(start, width float64, count int) []float64 {
+	bounds := make([]float64, 0, count)
+	for i := 0; i < count; i++ {
+		bounds = append(bounds, start+float64(i)*width)
+	}
+	return bounds
+}
+
+// ExponentialBuckets 返回 count 个等比的边界 start, start*factor, ...
+// ExponentialBuckets returns count boundaries, starting at start, each factor times the previous.
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	bounds := make([]float64, 0, count)
+	for i := 0; i < count; i++ {
+		bounds = append(bounds, start)
+		start *= factor
+	}
+	return bounds
+}
+
+// Histogram 直方图: 按递增的边界 bounds 统计 value 落在每个桶中的个数, 边界可以使用 LinearBuckets, ExponentialBuckets 生成.
+// 返回 len(bounds)+1 个桶, 第一个桶为 [-Inf, bounds[0]), 最后一个为 [bounds[len-1], +Inf). 忽略 NaN.
+// value 必须返回数字(任意大小的 int, uint 或 float), 否则 panic; bounds 不是严格递增时 panic
+// Histogram counts the values in the buckets split by the increasing bounds.
+func (s *stream) Histogram(value types.Function, bounds []float64) []Bucket {
+	buckets := make([]Bucket, len(bounds)+1)
+	low := math.Inf(-1)
+	for i, high := range bounds {
+		if !(high > low) {
+			panic(fmt.Sprintf("stream: histogram bounds must be strictly increasing: %v", bounds))
+		}
+		buckets[i].Low, buckets[i].High = low, high
+		low = high
+	}
+	buckets[len(bounds)].Low, buckets[len(bounds)].High = low, math.Inf(1)
+	return s.ReduceWith(buckets, func(acc types.R, t types.T) types.R {
+		v := toFloat64(value(t))
+		if !math.IsNaN(v) {
+			// 第一个边界大于 v 的桶
+			acc.([]Bucket)[sort.Search(len(bounds), func(i int) bool { return bounds[i] > v })].Count++
+		}
+		return acc
+	}).([]Bucket)
+}
+
+// toFloat64 将数字转为 float64, 不是数字时 panic
+func toFloat64(v types.R) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	panic(fmt.Sprintf("stream: %T is not a number", v))
+}
@@ -31,32 +31,73 @@ import (
 //                +-------+----+----------+
 //
 //               <----- wrapped stage ----->
+//
+// 同一个头节点上的流只能执行一次终止操作, 因为数据源迭代器已被消费。
+// 头节点记录是否已被消费, 再次执行终止操作会 panic(ErrStreamConsumed).
+// 可重放的流(Replayable)每次终止操作都会通过 newSource 重新创建数据源。
 type stream struct {
-	source iterator // 数据源
-	prev   *stream  // 前一个流
-	wrap   func(stage) stage
+	head      *stream // 头节点, 头节点的 head 是自身
+	prev      *stream // 前一个流
+	wrap      func(stage) stage
+	stateless bool // 是否是无状态操作, 无状态操作可以切分数据源后并行执行
+
+	// 以下字段只在头节点上使用
+	newSource  func() iterator // 创建数据源
+	consumed   bool            // 是否已执行过终止操作
+	replayable bool            // 是否可重放
 }
 
 // region help methods 帮助方法
 
 // newHead 构造头节点
-func newHead(source iterator) *stream {
-	return &stream{source: source}
+func newHead(newSource func() iterator) *stream {
+	s := &stream{newSource: newSource}
+	s.head = s
+	return s
 }
 
 // newNode 构造中间节点
 func newNode(prev *stream, wrap func(down stage) stage) *stream {
 	return &stream{
-		source: prev.source,
-		prev:   prev,
-		wrap:   wrap,
+		head: prev.head,
+		prev: prev,
+		wrap: wrap,
 	}
 }
 
+// newStatelessNode 构造无状态操作的中间节点
+func newStatelessNode(prev *stream, wrap func(down stage) stage) *stream {
+	s := newNode(prev, wrap)
+	s.stateless = true
+	return s
+}
+
+// open 打开数据源。流只能被消费一次，除非是可重放的
+func (s *stream) open() iterator {
+	head := s.head
+	if head.consumed && !head.replayable {
+		panic(ErrStreamConsumed)
+	}
+	head.consumed = true
+	return head.newSource()
+}
+
 // terminal 终止操作调用。触发包装各项操作，开始元素遍历
 func (s *stream) terminal(ts *terminalStage) {
-	stage := s.wrapStage(ts)
-	source := s.source
+	source := s.open()
+	if c, ok := source.(closer); ok {
+		defer c.close() // 提前结束或 panic 时释放数据源
+	}
+	s.run(source, ts)
+}
+
+// run 包装各项操作，从数据源 source 开始元素遍历
+func (s *stream) run(source iterator, ts stage) {
+	drive(source, s.wrapStage(ts))
+}
+
+// drive 迭代数据源 source 中的每个元素给 stage
+func drive(source iterator, stage stage) {
 	stage.Begin(source.GetSizeIfKnown())
 	for source.HasNext() && !stage.CanFinish() {
 		stage.Accept(source.Next())
@@ -73,6 +114,17 @@ func (s *stream) wrapStage(terminalStage stage) stage {
 	return stage
 }
 
+// Replayable 标记流可以重放: 默认每个流只能执行一次终止操作, 否则 panic(ErrStreamConsumed);
+// 可重放的流每次终止操作都会从重新创建的数据源开始迭代. 对共享同一数据源的整条流生效, 返回流本身.
+// 不是本包创建的 Stream 会先被收集为切片.
+// Replayable marks the stream can be consumed more than once, each terminal operate re-creates the source.
+// It applies to the whole chain which shares the same head, and returns the stream itself.
+func Replayable(s Stream) Stream {
+	ss := asStream(s)
+	ss.head.replayable = true
+	return ss
+}
+
 // endregion 帮助方法
 
 // region 无状态操作
@@ -80,7 +132,7 @@ func (s *stream) wrapStage(terminalStage stage) stage {
 // Filter 过滤操作
 // test is a Predicate, return true then keep the element 返回 true 的将保留
 func (s *stream) Filter(test types.Predicate) Stream {
-	return newNode(s, func(down stage) stage {
+	return newStatelessNode(s, func(down stage) stage {
 		return newChainedStage(down, begin(func(int64) {
 			down.Begin(unknownSize) // 过滤后个数不确定
 		}), action(func(t types.T) {
@@ -94,28 +146,116 @@ func (s *stream) Filter(test types.Predicate) Stream {
 // Map 转换操作
 // apply is a Function, convert the element to another 转换元素
 func (s *stream) Map(apply types.Function) Stream {
-	return newNode(s, func(down stage) stage {
+	return newStatelessNode(s, func(down stage) stage {
 		return newChainedStage(down, action(func(t types.T) {
 			down.Accept(apply(t))
 		}))
 	})
 }
 
+// MapRetry 转换操作, fn 返回错误时按 policy 重试. 重试耗尽(或错误不可重试)时,
+// 如果设置了 policy.DeadLetter, 将元素和错误交给它并跳过该元素, 否则 panic(*RetryError)
+// MapRetry like Map, but retries fn by the policy when it returns an error.
+func (s *stream) MapRetry(fn func(types.T) (types.R, error), policy RetryPolicy) Stream {
+	return newNode(s, func(down stage) stage {
+		return newChainedStage(down, begin(func(int64) {
+			down.Begin(unknownSize) // 失败的元素可能被跳过
+		}), action(func(t types.T) {
+			r, attempts, err := policy.call(t, fn)
+			switch {
+			case err == nil:
+				down.Accept(r)
+			case policy.DeadLetter != nil:
+				policy.DeadLetter(t, err)
+			default:
+				panic(&RetryError{Element: t, Attempts: attempts, Err: err})
+			}
+		}))
+	})
+}
+
+// MapOrElse 转换操作, fn 返回错误时使用 fallback 的结果
+// MapOrElse like Map, but uses fallback(element, err) as the result when fn returns an error.
+func (s *stream) MapOrElse(fn func(types.T) (types.R, error), fallback func(types.T, error) types.R) Stream {
+	return newStatelessNode(s, func(down stage) stage {
+		return newChainedStage(down, action(func(t types.T) {
+			r, err := fn(t)
+			if err != nil {
+				r = fallback(t, err)
+			}
+			down.Accept(r)
+		}))
+	})
+}
+
 // FlatMap 打平集合为元素。[[1,2],[3,4]] -> [1,2,3,4]
 func (s *stream) FlatMap(flatten func(t types.T) Stream) Stream {
-	return newNode(s, func(down stage) stage {
+	return newStatelessNode(s, func(down stage) stage {
 		return newChainedStage(down, begin(func(int64) {
 			down.Begin(unknownSize) // 最终个数不确定
 		}), action(func(t types.T) {
-			ss := flatten(t)        // 元素是集合，转为流
-			ss.ForEach(down.Accept) // 消费流中的元素
+			ss := flatten(t)   // 元素是集合，转为流
+			consume(ss, down) // 消费流中的元素
+		}))
+	})
+}
+
+// FlatMapSlice 打平切片。flatten 返回值必须是切片类型(或 nil), 否则 panic(ErrNotSlice)
+// FlatMapSlice like FlatMap, but the flatten function returns a slice of any type.
+func (s *stream) FlatMapSlice(flatten types.Function) Stream {
+	return newStatelessNode(s, func(down stage) stage {
+		return newChainedStage(down, begin(func(int64) {
+			down.Begin(unknownSize)
+		}), action(func(t types.T) {
+			consumeSlice(flatten(t), down)
 		}))
 	})
 }
 
+// Flatten 元素本身是切片，打平为元素。[[1,2],[3,4]] -> [1,2,3,4]
+// Flatten each element must be a slice(or nil), flatten them to elements.
+func (s *stream) Flatten() Stream {
+	return s.FlatMapSlice(func(t types.T) types.R {
+		return t
+	})
+}
+
+// consume 将流 ss 中的元素依次发送给 down, 下游可以提前结束时不再继续迭代 ss
+func consume(ss Stream, down stage) {
+	if inner, ok := ss.(*stream); ok {
+		inner.terminal(newTerminalStage(down.Accept, canFinish(down.CanFinish)))
+		return
+	}
+	// 其他 Stream 实现
+	ss.AnyMatch(func(t types.T) bool {
+		down.Accept(t)
+		return down.CanFinish()
+	})
+}
+
+// consumeSlice 将切片 slice 中的元素依次发送给 down, 下游可以提前结束时不再继续迭代
+func consumeSlice(slice types.T, down stage) {
+	if optional.IsNil(slice) {
+		return
+	}
+	if elements, ok := slice.([]types.T); ok {
+		for i := 0; i < len(elements) && !down.CanFinish(); i++ {
+			down.Accept(elements[i])
+		}
+		return
+	}
+	if reflect.TypeOf(slice).Kind() != reflect.Slice {
+		panic(ErrNotSlice)
+	}
+	value := reflect.ValueOf(slice)
+	for i := 0; i < value.Len() && !down.CanFinish(); i++ {
+		down.Accept(value.Index(i).Interface())
+	}
+}
+
 // Peek visit every element and leave them on stream so that they can be operated by next action  访问流中每个元素而不消费它，可用于 debug
 func (s *stream) Peek(consumer types.Consumer) Stream {
-	return newNode(s, func(down stage) stage {
+	return newStatelessNode(s, func(down stage) stage {
 		return newChainedStage(down, action(func(t types.T) {
 			consumer(t)
 			down.Accept(t)
@@ -180,6 +320,34 @@ func (s *stream) Sorted(cmp types.Comparator) Stream {
 	})
 }
 
+// ParallelSorted 并行稳定排序, 相等的元素保持原有顺序. 收集所有元素后, 切分为至多 parallelism 块并行排序, 再两两并行合并.
+// 元素少于 parallelSortThreshold 时按顺序排序. cmp 需要能被并发调用.
+// parallelism 不大于 0 时使用 runtime.GOMAXPROCS(0)
+func (s *stream) ParallelSorted(cmp types.Comparator, parallelism int) Stream {
+	return newNode(s, func(down stage) stage {
+		var list []types.T
+		return newChainedStage(down, begin(func(size int64) {
+			if size > 0 {
+				list = make([]types.T, 0, size)
+			} else {
+				list = make([]types.T, 0)
+			}
+			down.Begin(size)
+		}), action(func(t types.T) {
+			list = append(list, t)
+		}), end(func() {
+			parallelSort(list, cmp, parallelismOf(parallelism))
+			down.Begin(int64(len(list)))
+			i := it(list...)
+			for i.HasNext() && !down.CanFinish() {
+				down.Accept(i.Next())
+			}
+			list = nil
+			down.End()
+		}))
+	})
+}
+
 // Limit 限制元素个数
 func (s *stream) Limit(maxSize int64) Stream {
 	return newNode(s, func(down stage) stage {
@@ -14,6 +14,14 @@ type iterator interface {
 	Next() types.T
 }
 
+// splittable 可以按下标区间切分的迭代器, 用于并行操作.
+// 切分出的迭代器与原迭代器相互独立
+type splittable interface {
+	iterator
+	// sub 返回 [fromInclude, toExclude) 区间内元素的迭代器
+	sub(fromInclude, toExclude int) iterator
+}
+
 func it(elements ...types.T) iterator {
 	return &sliceIterator{
 		base: &base{
@@ -68,6 +76,10 @@ func (s *sliceIterator) Next() types.T {
 	return e
 }
 
+func (s *sliceIterator) sub(fromInclude, toExclude int) iterator {
+	return it(s.elements[fromInclude:toExclude]...)
+}
+
 // endregion sliceIterator
 
 type intsIt struct {
@@ -81,6 +93,13 @@ func (i *intsIt) Next() types.T {
 	return e
 }
 
+func (i *intsIt) sub(fromInclude, toExclude int) iterator {
+	return &intsIt{
+		base:     &base{current: 0, size: toExclude - fromInclude},
+		elements: i.elements[fromInclude:toExclude],
+	}
+}
+
 type int64sIt struct {
 	*base
 	elements []int64
@@ -92,6 +111,13 @@ func (i *int64sIt) Next() types.T {
 	return e
 }
 
+func (i *int64sIt) sub(fromInclude, toExclude int) iterator {
+	return &int64sIt{
+		base:     &base{current: 0, size: toExclude - fromInclude},
+		elements: i.elements[fromInclude:toExclude],
+	}
+}
+
 type float32sIt struct {
 	*base
 	elements []float32
@@ -103,6 +129,13 @@ func (i *float32sIt) Next() types.T {
 	return e
 }
 
+func (i *float32sIt) sub(fromInclude, toExclude int) iterator {
+	return &float32sIt{
+		base:     &base{current: 0, size: toExclude - fromInclude},
+		elements: i.elements[fromInclude:toExclude],
+	}
+}
+
 type float64sIt struct {
 	*base
 	elements []float64
@@ -114,6 +147,13 @@ func (i *float64sIt) Next() types.T {
 	return e
 }
 
+func (i *float64sIt) sub(fromInclude, toExclude int) iterator {
+	return &float64sIt{
+		base:     &base{current: 0, size: toExclude - fromInclude},
+		elements: i.elements[fromInclude:toExclude],
+	}
+}
+
 type stringIt struct {
 	*base
 	elements []string
@@ -125,6 +165,13 @@ func (i *stringIt) Next() types.T {
 	return e
 }
 
+func (i *stringIt) sub(fromInclude, toExclude int) iterator {
+	return &stringIt{
+		base:     &base{current: 0, size: toExclude - fromInclude},
+		elements: i.elements[fromInclude:toExclude],
+	}
+}
+
 // region sliceIt
 
 // sliceIt 切片迭代器 反射实现
@@ -140,6 +187,13 @@ func (s *sliceIt) Next() types.T {
 	return e
 }
 
+func (s *sliceIt) sub(fromInclude, toExclude int) iterator {
+	return &sliceIt{
+		base:       &base{current: 0, size: toExclude - fromInclude},
+		sliceValue: s.sliceValue.Slice(fromInclude, toExclude),
+	}
+}
+
 // endregion sliceIt
 
 type mapIt struct {
@@ -0,0 +1,283 @@
+package stream
+
+import (
+	"github.com/youthlin/stream/optional"
+	"github.com/youthlin/stream/types"
+)
+
+// Join 哈希内连接, 对每对 key 相等的 (left, right) 返回一个 types.Pair{First: left, Second: right}.
+// 右侧在第一次终止操作开始时被收集为切片(可重放的流再次执行终止操作时复用), 哈希表建立在较小的一侧:
+// 左侧个数已知且比右侧少时建立在左侧, 先收集左侧, 输出按右侧的顺序; 否则建立在右侧, 左侧按顺序流式处理, 输出按左侧的顺序.
+// key 必须是可比较的
+// Join is a hash inner join. The right side is collected when the first terminal operate begins,
+// and reused when a Replayable stream is consumed again. The hash table is built on the smaller side:
+// on the left if its size is known and less than the right, then the output follows the right order;
+// otherwise on the right, then the left is streamed and the output follows the left order.
+// Keys must be comparable.
+func Join(left, right Stream, leftKey, rightKey types.Function) Stream {
+	return hashJoin(left, right, leftKey, rightKey, func(l types.T, r types.T) types.R {
+		return types.Pair{First: l, Second: r}
+	}, nil, nil)
+}
+
+// LeftJoin 哈希左外连接, 同 Join, 但没有匹配的左侧元素也会返回一次, 其 Second 为空的 optional.Optional;
+// 有匹配时 Second 为 optional.OfNullable(right). 哈希表建立在左侧时, 没有匹配的左侧元素在最后按左侧顺序返回.
+// optional.Optional 不能保存 nil, 所以匹配到的 nil 右侧元素也是空的 optional, 与没有匹配无法区分;
+// 右侧可能有 nil 元素时, 请先将元素包装(如放入结构体)再连接
+// LeftJoin is a hash left outer join, Second of each Pair is an optional.Optional.
+// When the hash table is built on the left, the left elements without match are returned last, in left order.
+// An optional.Optional can not hold nil, so a matched nil right element is an empty optional too,
+// which looks the same as no match; wrap the elements (e.g. in a struct) before joining if the right may contain nil.
+func LeftJoin(left, right Stream, leftKey, rightKey types.Function) Stream {
+	return hashJoin(left, right, leftKey, rightKey, func(l types.T, r types.T) types.R {
+		return types.Pair{First: l, Second: optional.OfNullable(r)}
+	}, func(l types.T) types.R {
+		return types.Pair{First: l, Second: optional.Empty()}
+	}, nil)
+}
+
+// FullOuterJoin 哈希全外连接, 同 Join, 但任一侧没有匹配的元素也会返回一次, 其另一侧为空.
+// Pair 的两侧都是 optional.Optional; 建立哈希表一侧没有匹配的元素在最后按该侧的顺序返回.
+// 与 LeftJoin 相同, 匹配到的 nil 元素也是空的 optional, 与没有匹配无法区分
+// FullOuterJoin is a hash full outer join, both sides of each Pair are optional.Optional.
+// The elements without match of the side which the hash table is built on are returned last, in their order.
+// Like LeftJoin, a matched nil element is an empty optional, which looks the same as no match.
+func FullOuterJoin(left, right Stream, leftKey, rightKey types.Function) Stream {
+	return hashJoin(left, right, leftKey, rightKey, func(l types.T, r types.T) types.R {
+		return types.Pair{First: optional.OfNullable(l), Second: optional.OfNullable(r)}
+	}, func(l types.T) types.R {
+		return types.Pair{First: optional.OfNullable(l), Second: optional.Empty()}
+	}, func(r types.T) types.R {
+		return types.Pair{First: optional.Empty(), Second: optional.OfNullable(r)}
+	})
+}
+
+// SemiJoin 半连接: 按左侧顺序返回在右侧存在相同 key 的左侧元素. 内存中只保存右侧的 key
+// SemiJoin returns the left elements which have a right element with the same key.
+func SemiJoin(left, right Stream, leftKey, rightKey types.Function) Stream {
+	return filterByKeys(left, right, leftKey, rightKey, true)
+}
+
+// AntiJoin 反连接: 按左侧顺序返回在右侧不存在相同 key 的左侧元素. 内存中只保存右侧的 key
+// AntiJoin returns the left elements which have no right element with the same key.
+func AntiJoin(left, right Stream, leftKey, rightKey types.Function) Stream {
+	return filterByKeys(left, right, leftKey, rightKey, false)
+}
+
+// SortedMergeJoin 排序合并内连接, 两侧都需要按 key 以 cmp 升序排列.
+// 两侧各在一个 goroutine 中执行(见 Buffered), 内存中只保存右侧与当前 key 相同的元素.
+// 输出按 key 排序, 相同 key 的结果按左侧顺序、再按右侧顺序
+// SortedMergeJoin is an inner join of two streams sorted by key, neither side is held in memory.
+func SortedMergeJoin(left, right Stream, leftKey, rightKey types.Function, cmp types.Comparator) Stream {
+	l, r := asStream(left), asStream(right)
+	return newHead(func() iterator {
+		return &mergeJoinIt{
+			left:     newBufferedIt(l, 0),
+			right:    newBufferedIt(r, 0),
+			leftKey:  leftKey,
+			rightKey: rightKey,
+			cmp:      cmp,
+		}
+	})
+}
+
+// asStream 其他 Stream 实现先收集为切片
+func asStream(s Stream) *stream {
+	if ss, ok := s.(*stream); ok {
+		return ss
+	}
+	return Of(s.ToSlice()...).(*stream)
+}
+
+// materialized 只收集一次的流. 连接的右侧在第一次终止操作时收集, 左侧可重放时之后的终止操作复用该结果,
+// 否则右侧再次被消费会 panic(ErrStreamConsumed)
+type materialized struct {
+	s        Stream
+	elements []types.T
+	done     bool
+}
+
+func (m *materialized) get() []types.T {
+	if !m.done {
+		m.elements, m.done = m.s.ToSlice(), true
+	}
+	return m.elements
+}
+
+// joinTable 在 build 侧的元素上建立的哈希表
+type joinTable struct {
+	build   []types.T
+	index   map[interface{}][]int
+	matched []bool // 需要返回没有匹配的 build 元素时才记录
+}
+
+func newJoinTable(build []types.T, key types.Function, trackMatched bool) *joinTable {
+	t := &joinTable{build: build, index: make(map[interface{}][]int, len(build))}
+	for i, b := range build {
+		k := key(b)
+		t.index[k] = append(t.index[k], i)
+	}
+	if trackMatched {
+		t.matched = make([]bool, len(build))
+	}
+	return t
+}
+
+// probe 按 build 的顺序对 key 为 k 的元素调用 f, f 返回 false 时结束. 返回是否有匹配
+func (t *joinTable) probe(k interface{}, f func(b types.T) bool) bool {
+	indexes := t.index[k]
+	for _, i := range indexes {
+		if t.matched != nil {
+			t.matched[i] = true
+		}
+		if !f(t.build[i]) {
+			break
+		}
+	}
+	return len(indexes) > 0
+}
+
+// misses 按 build 的顺序对没有匹配的元素调用 f, f 返回 false 时结束
+func (t *joinTable) misses(f func(b types.T) bool) {
+	for i, ok := range t.matched {
+		if !ok && !f(t.build[i]) {
+			return
+		}
+	}
+}
+
+// hashJoin 在较小的一侧建立哈希表, 用另一侧的元素按顺序查找. 对每个匹配调用 match;
+// leftMiss/rightMiss 不为 nil 时, 对没有匹配的左侧/右侧元素调用, 建立哈希表一侧的在最后调用
+func hashJoin(left, right Stream, leftKey, rightKey types.Function,
+	match func(l, r types.T) types.R, leftMiss, rightMiss func(types.T) types.R) Stream {
+	rights := &materialized{s: right}
+	return newNode(asStream(left), func(down stage) stage {
+		var (
+			lefts []types.T // 哈希表建立在左侧时收集的左侧元素
+			table *joinTable
+		)
+		accept := func(e types.R) bool {
+			down.Accept(e)
+			return !down.CanFinish()
+		}
+		return newChainedStage(down, begin(func(size int64) {
+			rs := rights.get()
+			if size >= 0 && size < int64(len(rs)) {
+				lefts = make([]types.T, 0, size)
+			} else {
+				table = newJoinTable(rs, rightKey, rightMiss != nil)
+			}
+			down.Begin(unknownSize)
+		}), action(func(l types.T) {
+			if table == nil {
+				lefts = append(lefts, l)
+				return
+			}
+			if !table.probe(leftKey(l), func(r types.T) bool {
+				return accept(match(l, r))
+			}) && leftMiss != nil {
+				down.Accept(leftMiss(l))
+			}
+		}), end(func() {
+			if table == nil { // 哈希表建立在左侧, 按右侧的顺序查找
+				table = newJoinTable(lefts, leftKey, leftMiss != nil)
+				for _, r := range rights.get() {
+					if down.CanFinish() {
+						break
+					}
+					if !table.probe(rightKey(r), func(l types.T) bool {
+						return accept(match(l, r))
+					}) && rightMiss != nil {
+						down.Accept(rightMiss(r))
+					}
+				}
+				if leftMiss != nil && !down.CanFinish() {
+					table.misses(func(l types.T) bool { return accept(leftMiss(l)) })
+				}
+			} else if rightMiss != nil && !down.CanFinish() {
+				table.misses(func(r types.T) bool { return accept(rightMiss(r)) })
+			}
+			lefts, table = nil, nil
+			down.End()
+		}))
+	})
+}
+
+// filterByKeys 右侧的 key 只收集一次, 同 hashJoin
+func filterByKeys(left, right Stream, leftKey, rightKey types.Function, keep bool) Stream {
+	var keys map[interface{}]struct{}
+	return newNode(asStream(left), func(down stage) stage {
+		return newChainedStage(down, begin(func(int64) {
+			if keys == nil {
+				keys = make(map[interface{}]struct{})
+				right.ForEach(func(r types.T) {
+					keys[rightKey(r)] = struct{}{}
+				})
+			}
+			down.Begin(unknownSize)
+		}), action(func(l types.T) {
+			if _, ok := keys[leftKey(l)]; ok == keep {
+				down.Accept(l)
+			}
+		}))
+	})
+}
+
+// mergeJoinIt 排序合并连接的迭代器
+type mergeJoinIt struct {
+	left, right       *bufferedIt
+	leftKey, rightKey types.Function
+	cmp               types.Comparator
+
+	group []types.T   // 右侧与当前 key 相同的元素
+	l     types.T     // 当前与 group 配对的左侧元素
+	i     int         // 下一个配对的 group 下标
+	key   interface{} // group 的 key
+}
+
+func (m *mergeJoinIt) GetSizeIfKnown() int64 {
+	return unknownSize
+}
+
+func (m *mergeJoinIt) HasNext() bool {
+	if m.i < len(m.group) {
+		return true
+	}
+	// 当前左侧元素已配对完, 下一个左侧元素的 key 相同时继续与 group 配对
+	if len(m.group) > 0 && m.left.HasNext() && m.cmp(m.leftKey(m.left.peek()), m.key) == 0 {
+		m.l, m.i = m.left.Next(), 0
+		return true
+	}
+	m.group = m.group[:0]
+	for m.left.HasNext() && m.right.HasNext() {
+		lk, rk := m.leftKey(m.left.peek()), m.rightKey(m.right.peek())
+		c := m.cmp(lk, rk)
+		if c < 0 {
+			m.left.Next()
+			continue
+		}
+		if c > 0 {
+			m.right.Next()
+			continue
+		}
+		m.key = rk
+		for m.right.HasNext() && m.cmp(m.rightKey(m.right.peek()), rk) == 0 {
+			m.group = append(m.group, m.right.Next())
+		}
+		m.l, m.i = m.left.Next(), 0
+		return true
+	}
+	return false
+}
+
+func (m *mergeJoinIt) Next() types.T {
+	m.HasNext()
+	p := types.Pair{First: m.l, Second: m.group[m.i]}
+	m.i++
+	return p
+}
+
+func (m *mergeJoinIt) close() {
+	m.left.close()
+	m.right.close()
+}
@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"github.com/youthlin/stream/types"
+)
+
+// Operator is an operation not bound to any source, it can be applied to any Stream.
+// 不绑定数据源的操作, 可以应用到任意 Stream 上
+type Operator func(s Stream) Stream
+
+// Apply applies the operation to the Stream.
+func (op Operator) Apply(s Stream) Stream {
+	return op(s)
+}
+
+// Then returns an Operator which applies this operation first, then the next.
+// 先执行当前操作, 再执行 next
+func (op Operator) Then(next Operator) Operator {
+	return func(s Stream) Stream {
+		return next(op(s))
+	}
+}
+
+// Pipeline records a sequence of operations without a source,
+// so that it can be defined once and applied to many Streams.
+// Pipeline is immutable, each method returns a new Pipeline.
+// 记录一系列操作而不绑定数据源, 可以定义一次后应用到多个流上.
+// Pipeline 是不可变的, 每个方法都返回新的 Pipeline
+//
+//	normalize := stream.NewPipeline().Map(trim).Distinct(hash).Sorted(cmp)
+//	normalize.Apply(stream.Of(...)).ForEach(...)
+//	normalize.Apply(stream.OfSlice(...)).ToSlice()
+type Pipeline struct {
+	ops []Operator
+}
+
+// NewPipeline creates a Pipeline with the given operations.
+func NewPipeline(ops ...Operator) Pipeline {
+	return Pipeline{ops: append([]Operator(nil), ops...)}
+}
+
+// Then returns a new Pipeline with the operations appended.
+// another Pipeline can be appended by p.Then(another.Apply)
+// 追加操作. 追加另一个 Pipeline 可以使用 p.Then(another.Apply)
+func (p Pipeline) Then(ops ...Operator) Pipeline {
+	// 限制容量, 保证 append 时总是复制, 不影响原有 Pipeline
+	return Pipeline{ops: append(p.ops[:len(p.ops):len(p.ops)], ops...)}
+}
+
+// Apply applies all the operations to the Stream in order.
+// 依次将所有操作应用到 s 上
+func (p Pipeline) Apply(s Stream) Stream {
+	for _, op := range p.ops {
+		s = op(s)
+	}
+	return s
+}
+
+// Operator returns all the operations as one Operator.
+func (p Pipeline) Operator() Operator {
+	return p.Apply
+}
+
+// Filter see Stream.Filter
+func (p Pipeline) Filter(test types.Predicate) Pipeline {
+	return p.Then(func(s Stream) Stream { return s.Filter(test) })
+}
+
+// Map see Stream.Map
+func (p Pipeline) Map(apply types.Function) Pipeline {
+	return p.Then(func(s Stream) Stream { return s.Map(apply) })
+}
+
+// FlatMap see Stream.FlatMap
+func (p Pipeline) FlatMap(flatten func(t types.T) Stream) Pipeline {
+	return p.Then(func(s Stream) Stream { return s.FlatMap(flatten) })
+}
+
+// Peek see Stream.Peek
+func (p Pipeline) Peek(consumer types.Consumer) Pipeline {
+	return p.Then(func(s Stream) Stream { return s.Peek(consumer) })
+}
+
+// Distinct see Stream.Distinct
+func (p Pipeline) Distinct(distincter types.IntFunction) Pipeline {
+	return p.Then(func(s Stream) Stream { return s.Distinct(distincter) })
+}
+
+// Sorted see Stream.Sorted
+func (p Pipeline) Sorted(cmp types.Comparator) Pipeline {
+	return p.Then(func(s Stream) Stream { return s.Sorted(cmp) })
+}
+
+// Limit see Stream.Limit
+func (p Pipeline) Limit(maxSize int64) Pipeline {
+	return p.Then(func(s Stream) Stream { return s.Limit(maxSize) })
+}
+
+// Skip see Stream.Skip
+func (p Pipeline) Skip(n int64) Pipeline {
+	return p.Then(func(s Stream) Stream { return s.Skip(n) })
+}
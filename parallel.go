@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+
+	"github.com/youthlin/stream/types"
+)
+
+// parallelismOf 并行度, 不大于 0 时使用 GOMAXPROCS
+func parallelismOf(parallelism int) int {
+	if parallelism <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return parallelism
+}
+
+// chunks 将 [0, size) 均匀切分为至多 n 个非空区间.
+// 切分方式只取决于 size 和 n, 与执行时机无关, 因此并行计算的结果是确定的
+func chunks(size, n int) [][2]int {
+	if n > size {
+		n = size
+	}
+	result := make([][2]int, 0, n)
+	for i := 0; i < n; i++ {
+		result = append(result, [2]int{i * size / n, (i + 1) * size / n})
+	}
+	return result
+}
+
+// parallelDo 在 n 个 goroutine 上分别执行 f(0)...f(n-1), 等待全部完成.
+// 如果有 panic, 在调用方 goroutine 上重新抛出下标最小的那个
+func parallelDo(n int, f func(i int)) {
+	panics := make([]interface{}, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			defer func() {
+				panics[i] = recover()
+			}()
+			f(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, p := range panics {
+		if p != nil {
+			panic(p)
+		}
+	}
+}
+
+// combineTree 按固定的树形顺序两两合并结果: ((r0+r1)+(r2+r3))+...
+func combineTree(results []types.R, combiner types.BinaryOperator) types.R {
+	for len(results) > 1 {
+		next := make([]types.R, 0, (len(results)+1)/2)
+		for i := 0; i < len(results); i += 2 {
+			if i+1 < len(results) {
+				next = append(next, combiner(results[i], results[i+1]))
+			} else {
+				next = append(next, results[i])
+			}
+		}
+		results = next
+	}
+	return results[0]
+}
+
+// isStateless 从当前节点到头节点是否都是无状态操作
+func (s *stream) isStateless() bool {
+	for i := s; i.prev != nil; i = i.prev {
+		if !i.stateless {
+			return false
+		}
+	}
+	return true
+}
+
+// parts 将流切分为至多 n 个可以并行执行的部分, 每个部分将其元素交给给定的 stage.
+// 数据源是切片且所有操作都是无状态操作时, 直接切分数据源, 各部分并行执行所有操作;
+// 否则先按顺序执行所有操作, 再切分结果
+func (s *stream) parts(n int) []func(stage) {
+	source := s.open()
+	var parts []func(stage)
+	if sp, ok := source.(splittable); ok && s.isStateless() {
+		for _, c := range chunks(int(sp.GetSizeIfKnown()), n) {
+			sub := sp.sub(c[0], c[1])
+			parts = append(parts, func(ts stage) {
+				s.run(sub, ts)
+			})
+		}
+		return parts
+	}
+	if c, ok := source.(closer); ok {
+		defer c.close()
+	}
+	var elements []types.T
+	s.run(source, newTerminalStage(func(e types.T) {
+		elements = append(elements, e)
+	}))
+	for _, c := range chunks(len(elements), n) {
+		sub := it(elements[c[0]:c[1]]...)
+		parts = append(parts, func(ts stage) {
+			drive(sub, ts)
+		})
+	}
+	return parts
+}
+
+// ParallelReduce 并行归约. 将数据源切分为至多 parallelism 个区间, 每个区间从 identity 开始使用 accumulator 累计结果,
+// 再按固定的树形顺序使用 combiner 合并各区间的结果, 所以(例如浮点数求和)并行度相同时每次运行的结果是相同的.
+// 切分方式取决于并行度: combiner 或 accumulator 不满足结合律时, 不同的 parallelism 可能得到不同的结果.
+// parallelism 不大于 0 时使用 runtime.GOMAXPROCS(0), 其在不同机器上可能不同, 需要跨机器复现结果时应传入固定的 parallelism.
+// identity 会被每个区间共享, 不能被 accumulator 修改; Filter/Map 等操作的函数需要能被并发调用.
+func (s *stream) ParallelReduce(identity types.R, accumulator func(acc types.R, e types.T) types.R,
+	combiner types.BinaryOperator, parallelism int) types.R {
+	parts := s.parts(parallelismOf(parallelism))
+	if len(parts) == 0 {
+		return identity
+	}
+	results := make([]types.R, len(parts))
+	parallelDo(len(parts), func(i int) {
+		result := identity
+		parts[i](newTerminalStage(func(e types.T) {
+			result = accumulator(result, e)
+		}))
+		results[i] = result
+	})
+	return combineTree(results, combiner)
+}
+
+// parallelSortThreshold 元素少于该值时并行排序没有收益, 按顺序排序
+const parallelSortThreshold = 1 << 13
+
+// parallelSort 并行稳定排序: 切分为 n 块分别使用 sort.Stable 排序, 再逐层两两合并相邻的块, 同一层的合并并行执行
+func parallelSort(list []types.T, cmp types.Comparator, n int) {
+	if len(list) < parallelSortThreshold || n < 2 {
+		sort.Stable(&Sortable{List: list, Cmp: cmp})
+		return
+	}
+	runs := chunks(len(list), n)
+	parallelDo(len(runs), func(i int) {
+		sort.Stable(&Sortable{List: list[runs[i][0]:runs[i][1]], Cmp: cmp})
+	})
+	src, dst := list, make([]types.T, len(list))
+	for len(runs) > 1 {
+		next := make([][2]int, (len(runs)+1)/2)
+		parallelDo(len(next), func(i int) {
+			left := runs[2*i]
+			if 2*i+1 == len(runs) { // 落单的块直接复制
+				copy(dst[left[0]:left[1]], src[left[0]:left[1]])
+				next[i] = left
+				return
+			}
+			right := runs[2*i+1]
+			merge(dst[left[0]:right[1]], src[left[0]:left[1]], src[right[0]:right[1]], cmp)
+			next[i] = [2]int{left[0], right[1]}
+		})
+		runs = next
+		src, dst = dst, src
+	}
+	if &src[0] != &list[0] {
+		copy(list, src)
+	}
+}
+
+// merge 将有序的 left, right 合并到 dst. 相等时先取 left 中的元素, 所以是稳定的
+func merge(dst, left, right []types.T, cmp types.Comparator) {
+	i, j, k := 0, 0, 0
+	for i < len(left) && j < len(right) {
+		if cmp(right[j], left[i]) < 0 {
+			dst[k] = right[j]
+			j++
+		} else {
+			dst[k] = left[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], left[i:])
+	copy(dst[k:], right[j:])
+}
@@ -0,0 +1,115 @@
+package stream
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/youthlin/stream/clock"
+	"github.com/youthlin/stream/types"
+)
+
+// bucket 令牌桶, 容量为 burst, 每秒补充 rate 个令牌
+type bucket struct {
+	tokens float64
+	last   time.Time // 上次补充的时间
+}
+
+// refill 补充令牌到 now, 返回桶是否已满
+func (b *bucket) refill(now time.Time, rate float64, burst int) bool {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = math.Min(float64(burst), b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+	return b.tokens >= float64(burst)
+}
+
+// take 取一个令牌, 没有令牌时等待. ctx 取消时返回 false
+func (b *bucket) take(ctx context.Context, rate float64, burst int, clk clock.Clock) bool {
+	for {
+		b.refill(clk.Now(), rate, burst)
+		if b.tokens >= 1 {
+			b.tokens--
+			return true
+		}
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		if wait < 1 {
+			wait = 1
+		}
+		timer := clk.NewTimer(wait)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// RateLimit 令牌桶限流: 最多一次通过 burst 个元素, 之后每秒约通过 ratePerSecond 个元素, 初始时桶是满的.
+// ctx 取消时(包括等待令牌时)流提前结束. 时间来自 clk, 真实时间使用 clock.System().
+// ratePerSecond 不是正数(包括 NaN)或 burst 小于 1 时 panic
+// RateLimit paces the elements by a token bucket, the stream finishes early when ctx is cancelled.
+// It panics if ratePerSecond is not positive or burst is less than 1.
+func (s *stream) RateLimit(ctx context.Context, ratePerSecond float64, burst int, clk clock.Clock) Stream {
+	return s.rateLimit(ctx, nil, ratePerSecond, burst, clk)
+}
+
+// RateLimitBy 同 RateLimit, 但每个 key 有单独的令牌桶, key 必须是可比较的. 等待令牌的元素会阻塞其后的所有元素.
+// 已补满的令牌桶会被释放. 参数不合法时 panic, 同 RateLimit
+// RateLimitBy like RateLimit, but each key has its own token bucket, the key must be comparable.
+// It panics on the same arguments as RateLimit.
+func (s *stream) RateLimitBy(ctx context.Context, key types.Function, ratePerSecond float64, burst int, clk clock.Clock) Stream {
+	return s.rateLimit(ctx, key, ratePerSecond, burst, clk)
+}
+
+// rateLimit key 为 nil 时所有元素共用一个令牌桶
+func (s *stream) rateLimit(ctx context.Context, key types.Function, rate float64, burst int, clk clock.Clock) Stream {
+	if !(rate > 0) || burst < 1 { // !(rate > 0) 包括 NaN
+		panic("stream: RateLimit ratePerSecond must be positive and burst must be at least 1")
+	}
+	return newNode(s, func(down stage) stage {
+		var (
+			shared    *bucket
+			buckets   = make(map[interface{}]*bucket)
+			created   int // 上次清理后新建的令牌桶个数, 超过现有个数的一半时清理, 均摊开销为常数
+			cancelled bool
+		)
+		bucketOf := func(e types.T) *bucket {
+			if key == nil {
+				return shared
+			}
+			k := key(e)
+			b, ok := buckets[k]
+			if !ok {
+				if created++; created > len(buckets)/2 {
+					now := clk.Now()
+					for k, b := range buckets {
+						if b.refill(now, rate, burst) {
+							delete(buckets, k)
+						}
+					}
+					created = 0
+				}
+				b = &bucket{tokens: float64(burst), last: clk.Now()}
+				buckets[k] = b
+			}
+			return b
+		}
+		return newChainedStage(down, begin(func(int64) {
+			shared = &bucket{tokens: float64(burst), last: clk.Now()}
+			down.Begin(unknownSize) // ctx 取消时会提前结束
+		}), action(func(t types.T) {
+			if cancelled {
+				return
+			}
+			if !bucketOf(t).take(ctx, rate, burst, clk) {
+				cancelled = true
+				return
+			}
+			down.Accept(t)
+		}), canFinish(func() bool {
+			return cancelled || down.CanFinish()
+		}))
+	})
+}
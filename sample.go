@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"container/heap"
+	"math/rand"
+	"time"
+
+	"github.com/youthlin/stream/types"
+)
+
+// randOf rng 为 nil 时使用以当前时间为种子的随机数生成器
+func randOf(rng *rand.Rand) *rand.Rand {
+	if rng == nil {
+		return rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return rng
+}
+
+// SampleFraction 随机保留元素, 每个元素以概率 p 被保留(伯努利采样). rng 为 nil 时使用以当前时间为种子的随机数.
+// rng 不能被并发使用, 所以这是有状态操作: ParallelReduce 会先按顺序采样再切分, 相同的种子得到相同的结果
+// SampleFraction keeps each element with probability p independently.
+// It is stateful because rng is not safe for concurrent use: ParallelReduce samples sequentially before splitting,
+// so the same seed gives the same result.
+func (s *stream) SampleFraction(p float64, rng *rand.Rand) Stream {
+	return newNode(s, func(down stage) stage {
+		r := randOf(rng)
+		return newChainedStage(down, begin(func(int64) {
+			down.Begin(unknownSize) // 采样后个数不确定
+		}), action(func(t types.T) {
+			if r.Float64() < p {
+				down.Accept(t)
+			}
+		}))
+	})
+}
+
+// Shuffled 随机打乱: 收集所有元素后使用 Fisher–Yates 洗牌. rng 为 nil 时使用以当前时间为种子的随机数
+// Shuffled collects all elements and shuffles them by Fisher–Yates.
+func (s *stream) Shuffled(rng *rand.Rand) Stream {
+	return newNode(s, func(down stage) stage {
+		var list []types.T
+		return newChainedStage(down, begin(func(size int64) {
+			if size > 0 {
+				list = make([]types.T, 0, size)
+			} else {
+				list = make([]types.T, 0)
+			}
+			down.Begin(size)
+		}), action(func(t types.T) {
+			list = append(list, t)
+		}), end(func() {
+			randOf(rng).Shuffle(len(list), func(i, j int) {
+				list[i], list[j] = list[j], list[i]
+			})
+			down.Begin(int64(len(list)))
+			i := it(list...)
+			for i.HasNext() && !down.CanFinish() {
+				down.Accept(i.Next())
+			}
+			list = nil
+			down.End()
+		}))
+	})
+}
+
+// Sample 蓄水池抽样: 从个数未知的流中等概率地抽取 k 个元素(不足 k 个时返回全部), 内存中只保存 k 个元素.
+// 结果的顺序是不确定的. rng 为 nil 时使用以当前时间为种子的随机数
+// Sample returns k elements chosen uniformly by reservoir sampling, only k elements are held in memory.
+func (s *stream) Sample(k int, rng *rand.Rand) []types.T {
+	r := randOf(rng)
+	result := make([]types.T, 0)
+	var n int64
+	s.terminal(newTerminalStage(func(t types.T) {
+		n++
+		if len(result) < k {
+			result = append(result, t)
+		} else if j := r.Int63n(n); j < int64(k) {
+			result[j] = t
+		}
+	}))
+	return result
+}
+
+// WeightedSample 加权不放回抽样(A-Res 算法): 元素被选中的概率与 weight 成正比, 权重不是正数的元素不会被选中.
+// 内存中只保存 k 个元素, 结果按抽中的先后排列. rng 为 nil 时使用以当前时间为种子的随机数
+// WeightedSample chooses k elements without replacement, each with probability proportional to its weight.
+func (s *stream) WeightedSample(k int, weight func(types.T) float64, rng *rand.Rand) []types.T {
+	r := randOf(rng)
+	h := &weightedHeap{}
+	s.terminal(newTerminalStage(func(t types.T) {
+		w := weight(t)
+		if !(w > 0) || k <= 0 {
+			return
+		}
+		// 每个元素的键为 Exp(1)/w, 键最小的 k 个元素即为样本
+		key := r.ExpFloat64() / w
+		if h.Len() < k {
+			heap.Push(h, weighted{key: key, value: t})
+		} else if key < (*h)[0].key {
+			(*h)[0] = weighted{key: key, value: t}
+			heap.Fix(h, 0)
+		}
+	}))
+	result := make([]types.T, h.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(h).(weighted).value
+	}
+	return result
+}
+
+type weighted struct {
+	key   float64
+	value types.T
+}
+
+// weightedHeap 按 key 的最大堆
+type weightedHeap []weighted
+
+func (h weightedHeap) Len() int            { return len(h) }
+func (h weightedHeap) Less(i, j int) bool  { return h[i].key > h[j].key }
+func (h weightedHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *weightedHeap) Push(x interface{}) { *h = append(*h, x.(weighted)) }
+func (h *weightedHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
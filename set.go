@@ -0,0 +1,135 @@
+package stream
+
+import (
+	"github.com/youthlin/stream/types"
+)
+
+// 按 key 的集合操作: key 相同的元素视为同一个成员, key 必须是可比较的.
+// 结果按 key 去重, 并保持流式读取一侧的顺序. b 只在第一次终止操作时被读取(同 Join), 可重放的流再次执行终止操作时复用
+// Set operations by key, the key must be comparable, results are distinct by key.
+// b is read only at the first terminal operate (like Join), and reused when a Replayable stream is consumed again.
+
+// Union 并集: 先返回 a 中的不同元素, 再返回 b 中 key 不在 a 中的元素. b 在第一次需要时被收集
+// Union returns the distinct elements of a, then those of b whose key is not in a.
+func Union(a, b Stream, key types.Function) Stream {
+	bs := &materialized{s: b}
+	return newNode(asStream(a), func(down stage) stage {
+		var seen map[interface{}]struct{}
+		accept := func(t types.T) {
+			k := key(t)
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				down.Accept(t)
+			}
+		}
+		return newChainedStage(down, begin(func(int64) {
+			seen = make(map[interface{}]struct{})
+			down.Begin(unknownSize)
+		}), action(accept), end(func() {
+			if !down.CanFinish() { // 已经足够时不读取 b
+				for _, t := range bs.get() {
+					if down.CanFinish() {
+						break
+					}
+					accept(t)
+				}
+			}
+			seen = nil
+			down.End()
+		}))
+	})
+}
+
+// Intersect 交集: 按 a 的顺序返回 key 在 b 中的不同元素. 先缓存 b 的 key
+// Intersect returns the distinct elements of a whose key is in b.
+func Intersect(a, b Stream, key types.Function) Stream {
+	return exceptOrIntersect(a, b, key, true)
+}
+
+// Except 差集: 按 a 的顺序返回 key 不在 b 中的不同元素. 先缓存 b 的 key
+// Except returns the distinct elements of a whose key is not in b.
+func Except(a, b Stream, key types.Function) Stream {
+	return exceptOrIntersect(a, b, key, false)
+}
+
+// exceptOrIntersect b 的 key 只收集一次, 同 filterByKeys
+func exceptOrIntersect(a, b Stream, key types.Function, inB bool) Stream {
+	var keys map[interface{}]struct{}
+	return newNode(asStream(a), func(down stage) stage {
+		var seen map[interface{}]struct{}
+		return newChainedStage(down, begin(func(int64) {
+			if keys == nil {
+				keys = make(map[interface{}]struct{})
+				b.ForEach(func(t types.T) {
+					keys[key(t)] = struct{}{}
+				})
+			}
+			seen = make(map[interface{}]struct{})
+			down.Begin(unknownSize)
+		}), action(func(t types.T) {
+			k := key(t)
+			if _, ok := keys[k]; ok != inB {
+				return
+			}
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				down.Accept(t)
+			}
+		}), end(func() {
+			seen = nil
+			down.End()
+		}))
+	})
+}
+
+// SymmetricDifference 对称差: 返回 key 只在 a 或 b 其中之一出现的不同元素,
+// 先按 a 的顺序返回 a 中的, 再按 b 的顺序返回 b 中的. 先缓存 b 中的不同元素
+// SymmetricDifference returns the distinct elements whose key is in exactly one of a and b.
+func SymmetricDifference(a, b Stream, key types.Function) Stream {
+	var (
+		onlyB []types.T           // b 中的不同元素, 只收集一次
+		index map[interface{}]int // b 中的 key -> onlyB 中的下标
+	)
+	return newNode(asStream(a), func(down stage) stage {
+		var (
+			inA  []bool
+			seen map[interface{}]struct{}
+		)
+		return newChainedStage(down, begin(func(int64) {
+			if index == nil {
+				index = make(map[interface{}]int)
+				b.ForEach(func(t types.T) {
+					k := key(t)
+					if _, ok := index[k]; !ok {
+						index[k] = len(onlyB)
+						onlyB = append(onlyB, t)
+					}
+				})
+			}
+			seen = make(map[interface{}]struct{})
+			inA = make([]bool, len(onlyB))
+			down.Begin(unknownSize)
+		}), action(func(t types.T) {
+			k := key(t)
+			if i, ok := index[k]; ok {
+				inA[i] = true
+				return
+			}
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				down.Accept(t)
+			}
+		}), end(func() {
+			for i, t := range onlyB {
+				if down.CanFinish() {
+					break
+				}
+				if !inA[i] {
+					down.Accept(t)
+				}
+			}
+			inA, seen = nil, nil
+			down.End()
+		}))
+	})
+}
@@ -0,0 +1,71 @@
+package sketch
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/youthlin/stream"
+	"github.com/youthlin/stream/types"
+)
+
+// The CountMinSketch configuration of HeavyHitters:
+// the estimated count exceeds the true one by at most 0.1% of the total with probability 97%.
+// HeavyHitters 使用的 CountMinSketch 配置: 以 97% 的概率, 估计值最多比真实值大总数的 0.1%
+const (
+	DefaultWidth = 2048
+	DefaultDepth = 5
+)
+
+// ApproxCountDistinct counts the distinct keys of s by a HyperLogLog of the given precision, keys must be comparable.
+// The sketch is returned, call its Count method for the result, or merge and serialize it.
+// 使用给定 precision 的 HyperLogLog 统计不同 key 的个数, key 必须是可比较的.
+// 返回 sketch, 调用其 Count 方法获取结果, 也可以合并或序列化
+func ApproxCountDistinct(s stream.Stream, key types.Function, precision uint8) *HyperLogLog {
+	h := NewHyperLogLog(precision)
+	s.ForEach(func(e types.T) {
+		h.AddHash(Hash(key(e)))
+	})
+	return h
+}
+
+// HeavyHitters finds the k most frequent keys of s by a TopK with DefaultWidth and DefaultDepth, keys must be comparable.
+// Keys are counted by their Hash, like ApproxCountDistinct.
+// The sketch is returned, call its Top method for the result, or merge and serialize it.
+// 使用 TopK(DefaultWidth, DefaultDepth) 统计出现次数最多的 k 个 key, key 必须是可比较的.
+// 与 ApproxCountDistinct 一样按 key 的 Hash 计数. 返回 sketch, 调用其 Top 方法获取结果, 也可以合并或序列化
+func HeavyHitters(s stream.Stream, key types.Function, k int) *TopK {
+	t := NewTopK(k, DefaultWidth, DefaultDepth)
+	s.ForEach(func(e types.T) {
+		t.Add(key(e))
+	})
+	return t
+}
+
+// ApproxQuantiles summarizes the values of s by a TDigest with DefaultCompression.
+// value must return a number (int, uint or float of any size), or it panics.
+// The sketch is returned, call its Quantiles method for the result, or merge and serialize it.
+// 使用 TDigest(DefaultCompression) 汇总 value 的分布, value 必须返回数字(任意大小的 int, uint 或 float), 否则 panic.
+// 返回 sketch, 调用其 Quantiles 方法获取结果, 也可以合并或序列化
+func ApproxQuantiles(s stream.Stream, value types.Function) *TDigest {
+	t := NewTDigest(DefaultCompression)
+	s.ForEach(func(e types.T) {
+		t.Add(toFloat64(value(e)))
+	})
+	return t
+}
+
+func toFloat64(v types.R) float64 {
+	if f, ok := v.(float64); ok {
+		return f
+	}
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	panic(fmt.Sprintf("sketch: %T is not a number", v))
+}
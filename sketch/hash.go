@@ -0,0 +1,110 @@
+package sketch
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Hash returns a 64-bit hash of k for AddHash, k must be comparable.
+// Equal (==) values have equal hashes, and the result is stable across processes
+// except for values containing pointers, so sketches built in different processes can be merged.
+// It is the same as Hash of github.com/youthlin/stream/v2/sketch, so sketches built by both versions can be merged too.
+// 返回 k 的 64 位哈希值, 用于 AddHash, k 必须是可比较的. 相等的值哈希值相同.
+// 除了包含指针的值, 哈希值在不同进程中是稳定的, 所以不同进程中构建的 sketch 可以合并.
+// 与 v2 版本 sketch 包的 Hash 相同, 所以两个版本构建的 sketch 也可以合并
+func Hash(k interface{}) uint64 {
+	switch v := k.(type) {
+	case string:
+		return hashString(v)
+	case int:
+		return mix(uint64(v))
+	case int64:
+		return mix(uint64(v))
+	case float64:
+		return hashFloat(v)
+	}
+	// 其他类型(命名类型、结构体、数组、指针等): 按 == 的规则逐个字段/元素哈希
+	return hashValue(reflect.ValueOf(k))
+}
+
+// hashValue 按 == 的规则哈希可比较的值: 逐个哈希结构体字段和数组元素, 浮点数使用 hashFloat(所以 -0.0 与 0.0 相同),
+// 指针和通道按地址, 接口按其动态类型和值
+func hashValue(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Invalid: // nil
+		return mix(0)
+	case reflect.Bool:
+		if v.Bool() {
+			return mix(1)
+		}
+		return mix(0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return mix(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return mix(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return hashFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return combine(hashFloat(real(c)), hashFloat(imag(c)))
+	case reflect.String:
+		return hashString(v.String())
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return mix(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return mix(0)
+		}
+		e := v.Elem()
+		// 动态类型不同的值不相等, 类型名相同的不同类型只会导致哈希冲突
+		return combine(hashString(e.Type().String()), hashValue(e))
+	case reflect.Array:
+		h := mix(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			h = combine(h, hashValue(v.Index(i)))
+		}
+		return h
+	case reflect.Struct:
+		h := mix(uint64(v.NumField()))
+		for i := 0; i < v.NumField(); i++ {
+			h = combine(h, hashValue(v.Field(i)))
+		}
+		return h
+	}
+	panic(fmt.Sprintf("sketch: %s is not comparable", v.Type()))
+}
+
+// combine 组合两个哈希值, 与顺序有关
+func combine(h, x uint64) uint64 {
+	return mix(h + x)
+}
+
+// hashString FNV-1a 后混合各位
+func hashString(s string) uint64 {
+	const offset64, prime64 = 14695981039346656037, 1099511628211
+	h := uint64(offset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	return mix(h)
+}
+
+// hashFloat 0.0 与 -0.0 相等, 哈希值也相同
+func hashFloat(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+	return mix(math.Float64bits(f))
+}
+
+// mix splitmix64 的终结函数, 使相近的数哈希值无关
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
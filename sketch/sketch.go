@@ -0,0 +1,119 @@
+// Package sketch provides probabilistic summaries of large streams in small, fixed memory:
+// HyperLogLog for approximate distinct count, Count-Min Sketch based TopK for heavy hitters,
+// and t-digest for approximate quantiles.
+// Each sketch can be merged with another of the same configuration, e.g. built on different partitions,
+// and serialized by MarshalBinary / UnmarshalBinary.
+//
+// 概率数据结构, 使用固定的少量内存汇总大数据流: HyperLogLog 近似去重计数, 基于 Count-Min Sketch 的 TopK 统计高频元素,
+// t-digest 近似分位数. 相同配置的 sketch 可以合并(例如在不同分区上构建的), 也可以通过 MarshalBinary / UnmarshalBinary 序列化.
+package sketch
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+var (
+	// ErrIncompatible is returned when merging sketches of different configurations.
+	// 合并配置不同的 sketch 时返回
+	ErrIncompatible = errors.New("sketch: incompatible sketches")
+	// ErrInvalidData is returned when unmarshalling invalid data.
+	// 反序列化的数据无效时返回
+	ErrInvalidData = errors.New("sketch: invalid data")
+)
+
+// 序列化格式: 类型标记(1 字节) + 版本(1 字节) + 大端序的字段
+const (
+	tagHyperLogLog byte = iota + 1
+	tagCountMin
+	tagTopK
+	tagTDigest
+
+	version byte = 1
+)
+
+type encoder struct {
+	buf []byte
+}
+
+func newEncoder(tag byte) *encoder {
+	return &encoder{buf: []byte{tag, version}}
+}
+
+func (e *encoder) uint32(v uint32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *encoder) uint64(v uint64) {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *encoder) float64(v float64) {
+	e.uint64(math.Float64bits(v))
+}
+
+func (e *encoder) bytes(b []byte) {
+	e.uint32(uint32(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+// decoder 读取失败后 err 不为 nil, 之后的读取都返回零值
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func newDecoder(data []byte, tag byte) *decoder {
+	d := &decoder{buf: data}
+	if len(data) < 2 || data[0] != tag || data[1] != version {
+		d.err = ErrInvalidData
+		return d
+	}
+	d.buf = data[2:]
+	return d
+}
+
+func (d *decoder) next(n int) []byte {
+	if d.err != nil || n < 0 || len(d.buf) < n {
+		d.err = ErrInvalidData
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.next(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.next(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) float64() float64 {
+	return math.Float64frombits(d.uint64())
+}
+
+func (d *decoder) bytes() []byte {
+	return d.next(int(d.uint32()))
+}
+
+// finish 检查数据是否恰好读完
+func (d *decoder) finish() error {
+	if d.err == nil && len(d.buf) != 0 {
+		d.err = ErrInvalidData
+	}
+	return d.err
+}
@@ -0,0 +1,312 @@
+package sketch
+
+import (
+	"bytes"
+	"container/heap"
+	"encoding/gob"
+	"sort"
+)
+
+// CountMinSketch estimates the count of each element with a depth*width counter matrix,
+// the estimate is never less than the true count, and exceeds it by at most 2*Total()/width
+// with probability 1-(1/2)^depth.
+// CountMinSketch 使用 depth*width 的计数矩阵估计每个元素的出现次数, 估计值不小于真实值,
+// 并且以 1-(1/2)^depth 的概率最多比真实值大 2*Total()/width
+type CountMinSketch struct {
+	width, depth int
+	total        uint64
+	counts       []uint64
+}
+
+// NewCountMinSketch creates a CountMinSketch, it panics if width or depth is not positive.
+// 创建 CountMinSketch, width 或 depth 不是正数时 panic
+func NewCountMinSketch(width, depth int) *CountMinSketch {
+	if width <= 0 || depth <= 0 {
+		panic("sketch: CountMinSketch width and depth must be positive")
+	}
+	return &CountMinSketch{width: width, depth: depth, counts: make([]uint64, width*depth)}
+}
+
+// index 第 row 行的下标, 由哈希值的高低两部分组合出 depth 个哈希函数
+func (c *CountMinSketch) index(hash uint64, row int) int {
+	h1, h2 := hash&0xffffffff, hash>>32|1
+	return row*c.width + int((h1+uint64(row)*h2)%uint64(c.width))
+}
+
+// AddHash adds count to the element by its hash, see Hash.
+// 通过哈希值为元素增加 count 次计数, 见 Hash
+func (c *CountMinSketch) AddHash(hash, count uint64) {
+	c.total += count
+	for row := 0; row < c.depth; row++ {
+		c.counts[c.index(hash, row)] += count
+	}
+}
+
+// EstimateHash returns the estimated count of the element by its hash.
+// 通过哈希值估计元素的出现次数
+func (c *CountMinSketch) EstimateHash(hash uint64) uint64 {
+	var min uint64
+	for row := 0; row < c.depth; row++ {
+		if n := c.counts[c.index(hash, row)]; row == 0 || n < min {
+			min = n
+		}
+	}
+	return min
+}
+
+// Total returns the sum of all added counts.
+// 所有计数之和
+func (c *CountMinSketch) Total() uint64 {
+	return c.total
+}
+
+// Merge merges other into c, they must have the same width and depth.
+// 合并 other, 两者的 width 和 depth 必须相同, 否则返回 ErrIncompatible
+func (c *CountMinSketch) Merge(other *CountMinSketch) error {
+	if c.width != other.width || c.depth != other.depth {
+		return ErrIncompatible
+	}
+	c.total += other.total
+	for i, n := range other.counts {
+		c.counts[i] += n
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (c *CountMinSketch) MarshalBinary() ([]byte, error) {
+	e := newEncoder(tagCountMin)
+	c.encode(e)
+	return e.buf, nil
+}
+
+func (c *CountMinSketch) encode(e *encoder) {
+	e.uint32(uint32(c.width))
+	e.uint32(uint32(c.depth))
+	e.uint64(c.total)
+	for _, n := range c.counts {
+		e.uint64(n)
+	}
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (c *CountMinSketch) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, tagCountMin)
+	c.decode(d)
+	return d.finish()
+}
+
+func (c *CountMinSketch) decode(d *decoder) {
+	width, depth, total := int(d.uint32()), int(d.uint32()), d.uint64()
+	if d.err != nil || width <= 0 || depth <= 0 || uint64(width)*uint64(depth) > uint64(len(d.buf)/8) {
+		d.err = ErrInvalidData
+		return
+	}
+	counts := make([]uint64, width*depth)
+	for i := range counts {
+		counts[i] = d.uint64()
+	}
+	c.width, c.depth, c.total, c.counts = width, depth, total, counts
+}
+
+// Item is a key and its estimated count.
+// 元素及其估计的出现次数
+type Item struct {
+	Key   interface{}
+	Count uint64
+}
+
+// TopK tracks the k most frequent keys: counts are estimated by a CountMinSketch of the keys' Hash,
+// and a min-heap keeps the k keys with the largest estimates as candidates.
+// Keys with the same Hash are counted together, the first one added is kept as their representative.
+// TopK 统计出现次数最多的 k 个元素: 使用 CountMinSketch 按 key 的 Hash 估计次数, 使用最小堆保留估计值最大的 k 个元素作为候选.
+// Hash 相同的 key 合并统计, 保留最先加入的 key 作为代表
+type TopK struct {
+	k   int
+	cms *CountMinSketch
+	h   itemHeap
+}
+
+// NewTopK creates a TopK with a CountMinSketch of the given width and depth, it panics if k is not positive.
+// 创建 TopK, 使用给定 width 和 depth 的 CountMinSketch. k 不是正数时 panic
+func NewTopK(k, width, depth int) *TopK {
+	if k <= 0 {
+		panic("sketch: TopK k must be positive")
+	}
+	return &TopK{k: k, cms: NewCountMinSketch(width, depth), h: itemHeap{index: map[uint64]int{}}}
+}
+
+// Add adds one occurrence of key, key must be comparable.
+// 元素 key 出现一次
+func (t *TopK) Add(key interface{}) {
+	t.AddCount(key, 1)
+}
+
+// AddCount adds count occurrences of key.
+// 元素 key 出现 count 次
+func (t *TopK) AddCount(key interface{}, count uint64) {
+	hash := Hash(key)
+	t.cms.AddHash(hash, count)
+	t.offer(key, hash, t.cms.EstimateHash(hash))
+}
+
+// offer 更新候选: 已是候选则更新次数, 否则次数大于堆顶时替换堆顶
+func (t *TopK) offer(key interface{}, hash, count uint64) {
+	if i, ok := t.h.index[hash]; ok {
+		t.h.items[i].Count = count
+		heap.Fix(&t.h, i)
+		return
+	}
+	c := candidate{Item: Item{Key: key, Count: count}, hash: hash}
+	if len(t.h.items) < t.k {
+		heap.Push(&t.h, c)
+		return
+	}
+	if count > t.h.items[0].Count {
+		delete(t.h.index, t.h.items[0].hash)
+		t.h.items[0] = c
+		t.h.index[hash] = 0
+		heap.Fix(&t.h, 0)
+	}
+}
+
+// Estimate returns the estimated count of key, whether or not it is in the top k.
+// 估计 key 的出现次数, 不论其是否在前 k 个中
+func (t *TopK) Estimate(key interface{}) uint64 {
+	return t.cms.EstimateHash(Hash(key))
+}
+
+// Total returns the total number of occurrences added.
+// 所有元素出现的总次数
+func (t *TopK) Total() uint64 {
+	return t.cms.Total()
+}
+
+// Top returns at most k items, sorted by count descending, then by the Hash of key.
+// 返回最多 k 个元素, 按次数降序排列, 次数相同时按 key 的 Hash 排列
+func (t *TopK) Top() []Item {
+	candidates := append([]candidate(nil), t.h.items...)
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].before(candidates[j])
+	})
+	items := make([]Item, len(candidates))
+	for i, c := range candidates {
+		items[i] = c.Item
+	}
+	return items
+}
+
+// Merge merges other into t, they must have the same k and CountMinSketch configuration.
+// Candidates of both are re-estimated by the merged counts.
+// 合并 other, 两者的 k 和 CountMinSketch 配置必须相同, 否则返回 ErrIncompatible.
+// 两者的候选元素会使用合并后的计数重新估计
+func (t *TopK) Merge(other *TopK) error {
+	if t.k != other.k {
+		return ErrIncompatible
+	}
+	if err := t.cms.Merge(other.cms); err != nil {
+		return err
+	}
+	keys := make([]interface{}, 0, len(t.h.items)+len(other.h.items))
+	for _, c := range t.h.items {
+		keys = append(keys, c.Key)
+	}
+	for _, c := range other.h.items {
+		keys = append(keys, c.Key)
+	}
+	t.rebuild(keys)
+	return nil
+}
+
+// rebuild 使用当前计数重新估计候选元素
+func (t *TopK) rebuild(keys []interface{}) {
+	t.h = itemHeap{index: map[uint64]int{}}
+	for _, key := range keys {
+		hash := Hash(key)
+		t.offer(key, hash, t.cms.EstimateHash(hash))
+	}
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// The candidate keys are encoded by encoding/gob, keys of types other than the basic ones
+// (bool, numbers, string and their slices) must be registered by gob.Register.
+// 候选 key 使用 encoding/gob 编码, 基本类型(bool, 数字, string 及其切片)以外的 key 的类型需要使用 gob.Register 注册
+func (t *TopK) MarshalBinary() ([]byte, error) {
+	keys := make([]interface{}, len(t.h.items))
+	for i, c := range t.h.items {
+		keys[i] = c.Key
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(keys); err != nil {
+		return nil, err
+	}
+	e := newEncoder(tagTopK)
+	e.uint32(uint32(t.k))
+	t.cms.encode(e)
+	e.bytes(buf.Bytes())
+	return e.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (t *TopK) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, tagTopK)
+	k := int(d.uint32())
+	cms := &CountMinSketch{}
+	cms.decode(d)
+	encoded := d.bytes()
+	if err := d.finish(); err != nil {
+		return err
+	}
+	var keys []interface{}
+	if k <= 0 || gob.NewDecoder(bytes.NewReader(encoded)).Decode(&keys) != nil || len(keys) > k {
+		return ErrInvalidData
+	}
+	t.k, t.cms = k, cms
+	t.rebuild(keys)
+	return nil
+}
+
+// candidate 候选元素, hash 是 key 的 Hash
+type candidate struct {
+	Item
+	hash uint64
+}
+
+// before 按次数降序, 次数相同时按哈希值升序
+func (c candidate) before(o candidate) bool {
+	if c.Count != o.Count {
+		return c.Count > o.Count
+	}
+	return c.hash < o.hash
+}
+
+// itemHeap 按次数的最小堆, index 记录每个 key 的哈希值在堆中的下标
+type itemHeap struct {
+	items []candidate
+	index map[uint64]int
+}
+
+func (h *itemHeap) Len() int { return len(h.items) }
+
+func (h *itemHeap) Less(i, j int) bool {
+	return h.items[j].before(h.items[i])
+}
+
+func (h *itemHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+	h.index[h.items[i].hash] = i
+	h.index[h.items[j].hash] = j
+}
+
+func (h *itemHeap) Push(x interface{}) {
+	c := x.(candidate)
+	h.index[c.hash] = len(h.items)
+	h.items = append(h.items, c)
+}
+
+func (h *itemHeap) Pop() interface{} {
+	c := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	delete(h.index, c.hash)
+	return c
+}
@@ -1,8 +1,12 @@
 package stream
 
 import (
+	"context"
+	"math/rand"
 	"reflect"
+	"time"
 
+	"github.com/youthlin/stream/clock"
 	"github.com/youthlin/stream/optional"
 	"github.com/youthlin/stream/types"
 )
@@ -11,13 +15,24 @@ import (
 // It has stateless operates(Filter, Map, FlatMap, Peek),
 // stateful operates(Distinct, Sorted, Limit, Skip),
 // and the left methods are terminal operates.
+// Stream is implemented by this package only: new operates are added to the interface as methods,
+// which breaks implementations outside this package intentionally.
+// Stream 只由本包实现: 新的操作会作为方法加入接口, 本包之外的实现会因此无法编译, 这是有意的
 type Stream interface {
 	// stateless operate 无状态操作
 
 	Filter(types.Predicate) Stream         // 过滤
 	Map(types.Function) Stream             // 转换
 	FlatMap(func(t types.T) Stream) Stream // 打平
+	FlatMapSlice(types.Function) Stream    // 打平, Function 返回切片
+	Flatten() Stream                       // 打平, 元素本身是切片
 	Peek(types.Consumer) Stream            // peek 每个元素
+	// MapRetry 转换, fn 返回错误时按 policy 重试. MapRetry retries fn by the policy when it returns an error.
+	MapRetry(fn func(types.T) (types.R, error), policy RetryPolicy) Stream
+	// MapOrElse 转换, fn 返回错误时使用 fallback 的结果. MapOrElse uses fallback when fn returns an error.
+	MapOrElse(fn func(types.T) (types.R, error), fallback func(types.T, error) types.R) Stream
+	// SampleFraction 每个元素以概率 p 被保留. SampleFraction keeps each element with probability p.
+	SampleFraction(p float64, rng *rand.Rand) Stream
 
 	// stateful operate 有状态操作
 
@@ -25,6 +40,25 @@ type Stream interface {
 	Sorted(types.Comparator) Stream    // 排序
 	Limit(int64) Stream                // 限制个数
 	Skip(int64) Stream                 // 跳过个数
+	// DistinctWindow 只记住最近的 maxKeys 个标识的去重. DistinctWindow dedupes by the maxKeys most recent keys (LRU).
+	DistinctWindow(distincter types.IntFunction, maxKeys int) Stream
+	// DistinctApprox 使用布隆过滤器去重. DistinctApprox dedupes by a Bloom filter with false-positive rate fpRate.
+	DistinctApprox(distincter types.IntFunction, expectedN int, fpRate float64) Stream
+	// DistinctWithin 相同标识在 d 时间内只通过一次. DistinctWithin passes each key at most once within d.
+	DistinctWithin(distincter types.IntFunction, d time.Duration, clk clock.Clock) Stream
+	// ParallelSorted 并行稳定排序: 分块并行排序后稳定合并, 元素较少时按顺序排序.
+	// ParallelSorted sorts chunks concurrently and merges them stably,
+	// small streams are sorted sequentially.
+	ParallelSorted(cmp types.Comparator, parallelism int) Stream
+	// Buffered 在单独的 goroutine 中执行上游的操作, 预先取出最多 n 个元素.
+	// Buffered runs the upstream in its own goroutine, which keeps at most n elements ahead.
+	Buffered(n int) Stream
+	// RateLimit 令牌桶限流, ctx 取消时流提前结束. RateLimit paces elements by a token bucket.
+	RateLimit(ctx context.Context, ratePerSecond float64, burst int, clk clock.Clock) Stream
+	// RateLimitBy 每个 key 单独限流. RateLimitBy like RateLimit, but each key has its own token bucket.
+	RateLimitBy(ctx context.Context, key types.Function, ratePerSecond float64, burst int, clk clock.Clock) Stream
+	// Shuffled 随机打乱. Shuffled shuffles all elements by Fisher–Yates.
+	Shuffled(rng *rand.Rand) Stream
 
 	// terminal operate 终止操作
 
@@ -51,7 +85,22 @@ type Stream interface {
 	// ReduceBy use `buildInitValue` to build the initValue, which parameter is a int64 means element size, or -1 if unknown size.
 	// Then use `accumulator` to add each element to previous result
 	ReduceBy(buildInitValue func(sizeMayNegative int64) types.R, accumulator func(acc types.R, e types.T) types.R) types.R
+	// ParallelReduce 并行归约: 切分数据源后并行累计, 再按固定顺序使用 combiner 合并各部分结果, 并行度相同时结果是确定的.
+	// ParallelReduce split the source to at most `parallelism` ranges, reduce each range concurrently from `identity`,
+	// and combine the results in a fixed tree order, so the result is deterministic for a given parallelism.
+	ParallelReduce(identity types.R, accumulator func(acc types.R, e types.T) types.R, combiner types.BinaryOperator, parallelism int) types.R
 	FindFirst() optional.Optional
 	// 返回元素个数
 	Count() int64
+	// Sample 蓄水池抽样, 等概率抽取 k 个元素. Sample chooses k elements uniformly by reservoir sampling.
+	Sample(k int, rng *rand.Rand) []types.T
+	// WeightedSample 加权不放回抽样, 被选中的概率与权重成正比.
+	// WeightedSample chooses k elements without replacement, with probability proportional to weight.
+	WeightedSample(k int, weight func(types.T) float64, rng *rand.Rand) []types.T
+	// CountBy 按 key 统计个数. CountBy counts the elements by key.
+	CountBy(key types.Function) map[types.R]int64
+	// MostCommon 出现次数最多的 n 个 key 及次数. MostCommon returns the n most common keys with their counts.
+	MostCommon(key types.Function, n int) []types.Pair
+	// Histogram 按边界统计每个桶中值的个数. Histogram counts the values in the buckets split by bounds.
+	Histogram(value types.Function, bounds []float64) []Bucket
 }
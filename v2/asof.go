@@ -0,0 +1,61 @@
+package stream
+
+import (
+	"iter"
+	"time"
+
+	"github.com/youthlin/stream/v2/optional"
+	"github.com/youthlin/stream/v2/types"
+)
+
+// AsOfJoin pairs each left element with the latest right element of the same key
+// whose time is at or before the left element's time, e.g. each trade with the quote in effect.
+// If there is no such right element, or it is older than tolerance, the right side is absent.
+// tolerance <= 0 means no limit.
+// Both sides must be in ascending time order, they are streamed via iter.Pull,
+// only the latest right element of each key is held in memory,
+// and with a tolerance, keys not updated within it are released.
+// 时点连接: 将每个左侧元素与相同 key、时间不晚于它的最新右侧元素配对, 例如每笔成交与当时生效的报价.
+// 没有这样的右侧元素、或它早于左侧元素超过 tolerance 时, 右侧为空. tolerance 不大于 0 表示不限制.
+// 两侧都需要按时间升序排列, 通过 iter.Pull 流式读取, 内存中只保存每个 key 最新的右侧元素,
+// 设置了 tolerance 时, 超过 tolerance 没有更新的 key 会被释放
+func AsOfJoin[L, R any, K comparable](left iter.Seq[L], right iter.Seq[R],
+	leftTime types.Function[L, time.Time], rightTime types.Function[R, time.Time],
+	leftKey types.Function[L, K], rightKey types.Function[R, K], tolerance time.Duration) iter.Seq[types.Pair[L, types.Optional[R]]] {
+	type latest struct {
+		val R
+		at  time.Time
+	}
+	return func(yield func(types.Pair[L, types.Optional[R]]) bool) {
+		nextR, stopR := iter.Pull(right)
+		defer stopR()
+		r, okR := nextR()
+		latests := make(map[K]latest)
+		added := 0 // 上次清理后新增的 key 个数, 超过现有个数的一半时清理, 均摊开销为常数
+		for l := range left {
+			lt := leftTime(l)
+			for ; okR && !rightTime(r).After(lt); r, okR = nextR() {
+				k := rightKey(r)
+				if _, ok := latests[k]; !ok {
+					added++
+				}
+				latests[k] = latest{val: r, at: rightTime(r)}
+			}
+			if tolerance > 0 && added > len(latests)/2 {
+				for k, v := range latests {
+					if lt.Sub(v.at) > tolerance {
+						delete(latests, k)
+					}
+				}
+				added = 0
+			}
+			match := optional.Nil[R]()
+			if v, ok := latests[leftKey(l)]; ok && (tolerance <= 0 || lt.Sub(v.at) <= tolerance) {
+				match = optional.Of(v.val)
+			}
+			if !yield(types.Pair[L, types.Optional[R]]{First: l, Second: match}) {
+				return
+			}
+		}
+	}
+}
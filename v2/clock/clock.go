@@ -0,0 +1,48 @@
+// Package clock abstracts the time source of time-based operators,
+// so they can run on the real time, or on a Fake clock in tests without sleeping.
+// 时钟抽象, 基于时间的操作可以使用真实时间, 也可以在测试中使用 Fake 时钟而无需真正等待
+package clock
+
+import "time"
+
+// Clock is a source of time.
+// 时间源
+type Clock interface {
+	// Now 当前时间
+	Now() time.Time
+	// NewTimer 创建一个在 d 之后触发的 Timer
+	NewTimer(d time.Duration) Timer
+	// After 等价于 NewTimer(d).C()
+	After(d time.Duration) <-chan time.Time
+	// Sleep 等待 d
+	Sleep(d time.Duration)
+}
+
+// Timer is a single event created by a Clock, like time.Timer.
+// 单次定时器, 同 time.Timer
+type Timer interface {
+	// C 定时器触发时, 当前时间会被发送到该通道
+	C() <-chan time.Time
+	// Stop 停止定时器, 如果定时器已触发或已停止, 返回 false
+	Stop() bool
+}
+
+// System returns the Clock of the real time.
+// 返回真实时间的时钟
+func System() Clock {
+	return system{}
+}
+
+type system struct{}
+
+func (system) Now() time.Time                         { return time.Now() }
+func (system) NewTimer(d time.Duration) Timer         { return systemTimer{time.NewTimer(d)} }
+func (system) After(d time.Duration) <-chan time.Time { return time.After(d) }
+func (system) Sleep(d time.Duration)                  { time.Sleep(d) }
+
+type systemTimer struct {
+	t *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time { return t.t.C }
+func (t systemTimer) Stop() bool          { return t.t.Stop() }
@@ -0,0 +1,130 @@
+package clock
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// Fake is a Clock which only moves when Advance or Set is called,
+// timers whose deadline is reached fire synchronously inside Advance, in deadline order.
+// Fake is safe for concurrent use.
+// 只在调用 Advance 或 Set 时前进的时钟, 到期的定时器在 Advance 中按到期顺序同步触发. 可以并发使用
+type Fake struct {
+	mu      sync.Mutex
+	changed *sync.Cond // 定时器个数变化时通知 BlockUntil
+	now     time.Time
+	timers  []*fakeTimer
+}
+
+// NewFake creates a Fake clock whose current time is now.
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.changed = sync.NewCond(&f.mu)
+	return f
+}
+
+// Now returns the current time of the fake clock.
+func (f *Fake) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+// NewTimer creates a Timer which fires when the clock is advanced by d.
+// If d <= 0, the Timer fires immediately.
+func (f *Fake) NewTimer(d time.Duration) Timer {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := &fakeTimer{f: f, deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		return t
+	}
+	f.timers = append(f.timers, t)
+	f.changed.Broadcast()
+	return t
+}
+
+// After is NewTimer(d).C()
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	return f.NewTimer(d).C()
+}
+
+// Sleep blocks until the clock is advanced by d.
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+// Advance moves the clock forward by d and fires the timers whose deadline is reached.
+// 时钟前进 d, 触发到期的定时器
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(f.now.Add(d))
+}
+
+// Set moves the clock to t, and fires the timers whose deadline is reached.
+// The clock never moves backwards, a t before Now is ignored.
+// 时钟前进到 t, 触发到期的定时器; 时钟不会后退
+func (f *Fake) Set(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.set(t)
+}
+
+func (f *Fake) set(t time.Time) {
+	if t.Before(f.now) {
+		return
+	}
+	slices.SortStableFunc(f.timers, func(a, b *fakeTimer) int {
+		return a.deadline.Compare(b.deadline)
+	})
+	fired := 0
+	for _, timer := range f.timers {
+		if timer.deadline.After(t) {
+			break
+		}
+		f.now = timer.deadline // 触发时的时间是其到期时间
+		timer.c <- f.now
+		fired++
+	}
+	f.now = t
+	if fired > 0 {
+		f.timers = slices.Delete(f.timers, 0, fired)
+		f.changed.Broadcast()
+	}
+}
+
+// BlockUntil blocks until at least n timers (including Sleep and After) are waiting to fire.
+// Tests use it to wait for the code under test to arm its timers before calling Advance.
+// 阻塞直到至少有 n 个未触发的定时器(包括 Sleep 和 After). 测试中可以在 Advance 前等待被测代码设置好定时器
+func (f *Fake) BlockUntil(n int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for len(f.timers) < n {
+		f.changed.Wait()
+	}
+}
+
+type fakeTimer struct {
+	f        *Fake
+	deadline time.Time
+	c        chan time.Time
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.f.mu.Lock()
+	defer t.f.mu.Unlock()
+	i := slices.Index(t.f.timers, t)
+	if i < 0 {
+		return false
+	}
+	t.f.timers = slices.Delete(t.f.timers, i, i+1)
+	t.f.changed.Broadcast()
+	return true
+}
@@ -0,0 +1,158 @@
+package stream
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// outcome 一个任务的执行结果, 如果任务 panic 了, 记录 panic 的值
+type outcome[R any] struct {
+	val      R
+	panicked bool
+	panicVal any
+}
+
+// call 执行 f 并捕获 panic, 以便在消费者的 goroutine 上重新抛出
+func call[T, R any](ctx context.Context, f func(context.Context, T) R, v T) (o outcome[R]) {
+	defer func() {
+		if r := recover(); r != nil {
+			o.panicked, o.panicVal = true, r
+		}
+	}()
+	o.val = f(ctx, v)
+	return
+}
+
+// MapConcurrent transform elements on at most `workers` goroutines,
+// and yields the results in input order.
+// At most `workers` elements are in flight, so a slow element blocks the later results.
+// If the consumer stops early, all running goroutines are waited before return.
+// If f panics, the panic is re-raised on the consuming goroutine.
+// 使用最多 workers 个 goroutine 并发转换元素, 按输入顺序返回结果.
+// 消费者提前结束时, 会等待所有进行中的任务结束; f 中的 panic 会在消费者的 goroutine 上重新抛出
+func MapConcurrent[T, R any](it iter.Seq[T], workers int, f types.Function[T, R]) iter.Seq[R] {
+	return MapConcurrentContext(context.Background(), it, workers, ignoreContext(f))
+}
+
+// MapConcurrentContext like MapConcurrent, but f receives a Context,
+// which is cancelled when the consumer stops early, f panics or the parent ctx is cancelled.
+// The Seq ends when ctx is cancelled.
+// 同 MapConcurrent, 但 f 接收一个 Context, 消费者提前结束、f panic 或 ctx 取消时, 该 Context 会被取消
+func MapConcurrentContext[T, R any](ctx context.Context, it iter.Seq[T], workers int, f func(context.Context, T) R) iter.Seq[R] {
+	workers = max(workers, 1)
+	return func(yield func(R) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		defer func() {
+			cancel()
+			wg.Wait()
+		}()
+		next, stop := iter.Pull(it)
+		defer stop()
+		// 按输入顺序排队的任务结果, 每个 channel 有一个缓冲, 任务不会阻塞
+		pending := make([]chan outcome[R], 0, workers)
+		exhausted := false
+		for {
+			for !exhausted && len(pending) < workers && ctx.Err() == nil {
+				v, ok := next()
+				if !ok {
+					exhausted = true
+					break
+				}
+				ch := make(chan outcome[R], 1)
+				pending = append(pending, ch)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					ch <- call(ctx, f, v)
+				}()
+			}
+			if len(pending) == 0 {
+				return
+			}
+			var o outcome[R]
+			select {
+			case o = <-pending[0]:
+			case <-ctx.Done():
+				return
+			}
+			pending = pending[1:]
+			if o.panicked {
+				cancel()
+				wg.Wait()
+				panic(o.panicVal)
+			}
+			if !yield(o.val) {
+				return
+			}
+		}
+	}
+}
+
+// MapConcurrentUnordered like MapConcurrent, but yields results as they complete.
+// 同 MapConcurrent, 但按完成顺序返回结果
+func MapConcurrentUnordered[T, R any](it iter.Seq[T], workers int, f types.Function[T, R]) iter.Seq[R] {
+	return MapConcurrentUnorderedContext(context.Background(), it, workers, ignoreContext(f))
+}
+
+// MapConcurrentUnorderedContext like MapConcurrentContext, but yields results as they complete.
+// 同 MapConcurrentContext, 但按完成顺序返回结果
+func MapConcurrentUnorderedContext[T, R any](ctx context.Context, it iter.Seq[T], workers int, f func(context.Context, T) R) iter.Seq[R] {
+	workers = max(workers, 1)
+	return func(yield func(R) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		defer func() {
+			cancel()
+			wg.Wait()
+		}()
+		next, stop := iter.Pull(it)
+		defer stop()
+		// 最多 workers 个任务进行中, 缓冲足够所有任务写入结果而不阻塞
+		results := make(chan outcome[R], workers)
+		inflight := 0
+		exhausted := false
+		for {
+			for !exhausted && inflight < workers && ctx.Err() == nil {
+				v, ok := next()
+				if !ok {
+					exhausted = true
+					break
+				}
+				inflight++
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					results <- call(ctx, f, v)
+				}()
+			}
+			if inflight == 0 {
+				return
+			}
+			var o outcome[R]
+			select {
+			case o = <-results:
+			case <-ctx.Done():
+				return
+			}
+			inflight--
+			if o.panicked {
+				cancel()
+				wg.Wait()
+				panic(o.panicVal)
+			}
+			if !yield(o.val) {
+				return
+			}
+		}
+	}
+}
+
+func ignoreContext[T, R any](f types.Function[T, R]) func(context.Context, T) R {
+	return func(_ context.Context, v T) R {
+		return f(v)
+	}
+}
@@ -0,0 +1,140 @@
+package stream
+
+import (
+	"iter"
+
+	"github.com/youthlin/stream/v2/optional"
+	"github.com/youthlin/stream/v2/types"
+)
+
+// ChangeKind is the kind of a Change.
+// 变化类型
+type ChangeKind int
+
+const (
+	Unchanged ChangeKind = iota // 新旧都存在且相等
+	Added                       // 只在新的中存在
+	Removed                     // 只在旧的中存在
+	Modified                    // 新旧都存在但不相等
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Unchanged:
+		return "Unchanged"
+	case Added:
+		return "Added"
+	case Removed:
+		return "Removed"
+	case Modified:
+		return "Modified"
+	}
+	return "ChangeKind(?)"
+}
+
+// Change is a difference of an element between the old and the new Seq,
+// Old is absent for Added, New is absent for Removed.
+// 一个元素在新旧序列之间的变化, Added 时 Old 为空, Removed 时 New 为空
+type Change[T any] struct {
+	Kind ChangeKind
+	Old  types.Optional[T]
+	New  types.Optional[T]
+}
+
+// Diff compares the Seq before and after the change by key in any order: before is held in memory,
+// after is streamed, Added, Modified and Unchanged are yielded in the order of after,
+// then Removed in the order of before. Elements of the same key are paired in order.
+// equal reports whether the old and new element of the same key are equal.
+// 按 key 比较新旧序列, 顺序任意: 旧序列保存在内存中, 新序列流式读取.
+// 先按新序列的顺序返回 Added, Modified 和 Unchanged, 再按旧序列的顺序返回 Removed. 相同 key 的元素按顺序配对.
+// equal 判断相同 key 的新旧元素是否相等
+func Diff[T any, K comparable](before, after iter.Seq[T], key types.Function[T, K], equal func(o, n T) bool) iter.Seq[Change[T]] {
+	return func(yield func(Change[T]) bool) {
+		olds := Collect(before)
+		index := make(map[K][]int) // key -> 未配对的旧元素下标
+		for i, e := range olds {
+			k := key(e)
+			index[k] = append(index[k], i)
+		}
+		paired := make([]bool, len(olds))
+		for e := range after {
+			k := key(e)
+			indexes := index[k]
+			if len(indexes) == 0 {
+				if !yield(added(e)) {
+					return
+				}
+				continue
+			}
+			i := indexes[0]
+			index[k] = indexes[1:]
+			paired[i] = true
+			if !yield(changeOf(olds[i], e, equal)) {
+				return
+			}
+		}
+		for i, e := range olds {
+			if !paired[i] && !yield(removed(e)) {
+				return
+			}
+		}
+	}
+}
+
+// DiffSorted like Diff, but before and after must be sorted by key in ascending order of cmp,
+// they are streamed together via iter.Pull without being held in memory,
+// and the changes are yielded in key order.
+// 同 Diff, 但新旧序列都需要按 key 以 cmp 升序排列, 通过 iter.Pull 同时流式读取而不保存在内存中, 按 key 的顺序返回变化
+func DiffSorted[T, K any](before, after iter.Seq[T], key types.Function[T, K], cmp types.Comparator[K], equal func(o, n T) bool) iter.Seq[Change[T]] {
+	return func(yield func(Change[T]) bool) {
+		nextOld, stopOld := iter.Pull(before)
+		defer stopOld()
+		nextNew, stopNew := iter.Pull(after)
+		defer stopNew()
+		o, okOld := nextOld()
+		n, okNew := nextNew()
+		for okOld || okNew {
+			var c Change[T]
+			switch {
+			case !okNew:
+				c = removed(o)
+				o, okOld = nextOld()
+			case !okOld:
+				c = added(n)
+				n, okNew = nextNew()
+			default:
+				switch r := cmp(key(o), key(n)); {
+				case r < 0:
+					c = removed(o)
+					o, okOld = nextOld()
+				case r > 0:
+					c = added(n)
+					n, okNew = nextNew()
+				default:
+					c = changeOf(o, n, equal)
+					o, okOld = nextOld()
+					n, okNew = nextNew()
+				}
+			}
+			if !yield(c) {
+				return
+			}
+		}
+	}
+}
+
+func added[T any](e T) Change[T] {
+	return Change[T]{Kind: Added, Old: optional.Nil[T](), New: optional.Of(e)}
+}
+
+func removed[T any](e T) Change[T] {
+	return Change[T]{Kind: Removed, Old: optional.Of(e), New: optional.Nil[T]()}
+}
+
+func changeOf[T any](o, n T, equal func(o, n T) bool) Change[T] {
+	kind := Modified
+	if equal(o, n) {
+		kind = Unchanged
+	}
+	return Change[T]{Kind: kind, Old: optional.Of(o), New: optional.Of(n)}
+}
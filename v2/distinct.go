@@ -0,0 +1,125 @@
+package stream
+
+import (
+	"container/list"
+	"iter"
+	"math"
+	"time"
+
+	"github.com/youthlin/stream/v2/clock"
+	"github.com/youthlin/stream/v2/internal/hashing"
+	"github.com/youthlin/stream/v2/types"
+)
+
+// Distinct operations with bounded memory, for infinite or huge Seqs.
+// 内存有界的去重操作, 用于无限或很大的序列
+
+// DistinctWindow removes an element if its key is one of the maxKeys most recently seen keys (LRU),
+// a repeated key is refreshed. maxKeys less than 1 is treated as 1.
+// 近似去重: 只记住最近出现的 maxKeys 个不同的 key(LRU), 重复出现会刷新 key,
+// 所以与最近 maxKeys 个不同元素重复的元素会被移除. maxKeys 小于 1 时为 1
+func DistinctWindow[T any, K comparable](it iter.Seq[T], key types.Function[T, K], maxKeys int) iter.Seq[T] {
+	maxKeys = max(maxKeys, 1)
+	return func(yield func(T) bool) {
+		lru := list.New() // 最近出现的在前
+		keys := make(map[K]*list.Element)
+		for e := range it {
+			k := key(e)
+			if el, ok := keys[k]; ok {
+				lru.MoveToFront(el)
+				continue
+			}
+			keys[k] = lru.PushFront(k)
+			if lru.Len() > maxKeys {
+				delete(keys, lru.Remove(lru.Back()).(K))
+			}
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// DistinctApprox removes duplicates by a Bloom filter of about expectedN*1.44*log2(1/fpRate) bits.
+// Duplicates are never passed, but a distinct element may be wrongly removed:
+// with probability at most fpRate while there are no more than expectedN distinct keys, rising after that.
+// expectedN less than 1 is treated as 1, fpRate not in (0, 1) is treated as 0.01.
+// 使用布隆过滤器去重, 内存约为 expectedN*1.44*log2(1/fpRate) 位.
+// 不会漏掉重复元素, 但不重复的元素有一定概率被误判为重复而移除:
+// 不同元素不超过 expectedN 个时误判率不超过 fpRate, 超过后误判率逐渐升高.
+// expectedN 小于 1 时为 1, fpRate 不在 (0, 1) 范围内时为 0.01
+func DistinctApprox[T any, K comparable](it iter.Seq[T], key types.Function[T, K], expectedN int, fpRate float64) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		filter := newBloomFilter(expectedN, fpRate)
+		for e := range it {
+			if filter.add(hashing.Of(key(e))) && !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// DistinctWithin removes an element if an element with the same key passed within d,
+// after that the key can pass again. Only the keys passed within d are held in memory.
+// 按时间去重: 元素通过后, 相同 key 的元素在 d 时间内会被移除, 之后可以再次通过.
+// 内存中只保存 d 时间内通过的元素的 key. 时间来自 clk, 真实时间使用 clock.System()
+func DistinctWithin[T any, K comparable](it iter.Seq[T], key types.Function[T, K], d time.Duration, clk clock.Clock) iter.Seq[T] {
+	type passed struct {
+		key K
+		at  time.Time
+	}
+	return func(yield func(T) bool) {
+		var queue []passed // 按通过时间排序
+		keys := make(map[K]struct{})
+		for e := range it {
+			now := clk.Now()
+			// 过期的 key 一定在队首
+			i := 0
+			for ; i < len(queue) && now.Sub(queue[i].at) >= d; i++ {
+				delete(keys, queue[i].key)
+			}
+			queue = queue[i:]
+			k := key(e)
+			if _, ok := keys[k]; ok {
+				continue
+			}
+			keys[k] = struct{}{}
+			queue = append(queue, passed{key: k, at: now})
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// bloomFilter 布隆过滤器, 使用一个哈希值的高低两部分组合出 k 个哈希函数
+type bloomFilter struct {
+	bits []uint64
+	m    uint64 // 位数
+	k    uint64 // 哈希函数个数
+}
+
+func newBloomFilter(expectedN int, fpRate float64) *bloomFilter {
+	n := float64(max(expectedN, 1))
+	if !(fpRate > 0 && fpRate < 1) {
+		fpRate = 0.01
+	}
+	// 最优位数 m = -n*ln(p)/ln(2)^2, 哈希函数个数 k = m/n*ln(2)
+	m := uint64(math.Ceil(-n * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(max(1, math.Round(float64(m)/n*math.Ln2)))
+	return &bloomFilter{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// add 添加哈希值, 返回之前是否(可能)不存在
+func (b *bloomFilter) add(hash uint64) bool {
+	h1, h2 := hash&0xffffffff, hash>>32|1
+	added := false
+	for i := range b.k {
+		bit := (h1 + i*h2) % b.m
+		if word, mask := bit/64, uint64(1)<<(bit%64); b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	}
+	return added
+}
@@ -1,11 +1,24 @@
 package stream_test
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
 	"iter"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
 
 	"github.com/youthlin/stream/v2"
+	"github.com/youthlin/stream/v2/clock"
 	"github.com/youthlin/stream/v2/types"
 )
 
@@ -139,3 +152,1185 @@ func ExampleReduce() {
 	// 101
 	// true
 }
+
+func ExamplePipe() {
+	normalize := stream.Chain(
+		stream.MapOp(strings.TrimSpace),
+		stream.MapOp(strings.ToLower),
+		stream.DistinctOp(func(s string) string { return s }),
+		stream.SortedOp(strings.Compare),
+	)
+	fmt.Println(stream.Collect(normalize(stream.Of(" b", "A ", "a", "c").Seq())))
+	fmt.Println(stream.Collect(stream.Pipe(stream.Of("Y", "x", " y").Seq(), normalize, stream.LimitOp[string](1))))
+	// Output:
+	// [a b c]
+	// [x]
+}
+
+func ExamplePipe2() {
+	lengths := stream.Pipe2(
+		stream.Of("go", "stream", "iter").Seq(),
+		stream.MapOp(func(s string) int { return len(s) }),
+		stream.MapOp(func(n int) string { return strings.Repeat("*", n) }),
+	)
+	fmt.Println(stream.Collect(lengths))
+	parse := stream.Then(
+		stream.FilterOp(func(s string) bool { return s != "" }),
+		stream.MapOp(func(s string) int { return len(s) }),
+	)
+	fmt.Println(stream.Collect(parse.Apply(stream.Of("a", "", "bcd").Seq())))
+	// Output:
+	// [** ****** ****]
+	// [1 3]
+}
+
+func ExampleMapConcurrent() {
+	square := func(i int) int {
+		time.Sleep(time.Duration(10-i) * time.Millisecond) // later elements finish first
+		return i * i
+	}
+	fmt.Println(stream.Collect(stream.MapConcurrent(stream.Range(0, 10).Seq(), 4, square)))
+	unordered := stream.Collect(stream.MapConcurrentUnordered(stream.Range(0, 10).Seq(), 4, square))
+	slices.Sort(unordered)
+	fmt.Println(unordered)
+	// Output:
+	// [0 1 4 9 16 25 36 49 64 81]
+	// [0 1 4 9 16 25 36 49 64 81]
+}
+
+func TestMapConcurrentEarlyStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	var started atomic.Int32
+	slow := func(ctx context.Context, i int) int {
+		started.Add(1)
+		if i > 0 {
+			<-ctx.Done() // blocks until the consumer stops
+		}
+		return i
+	}
+	for _, mapConcurrent := range []func(context.Context, iter.Seq[int], int, func(context.Context, int) int) iter.Seq[int]{
+		stream.MapConcurrentContext[int, int],
+		stream.MapConcurrentUnorderedContext[int, int],
+	} {
+		for v := range mapConcurrent(context.Background(), stream.CountFrom(0).Seq(), 3, slow) {
+			if v != 0 {
+				t.Fatalf("got %d, want 0", v)
+			}
+			break
+		}
+	}
+	if n := started.Load(); n > 8 {
+		t.Errorf("started %d tasks, want at most 8", n)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("goroutines leaked: before=%d after=%d", before, after)
+	}
+}
+
+func TestMapConcurrentSharedSeq(t *testing.T) {
+	// a Seq can be ranged over by several goroutines at once (go test -race)
+	double := func(i int) int { return i * 2 }
+	for name, seq := range map[string]iter.Seq[int]{
+		"MapConcurrent":          stream.MapConcurrent(stream.Range(0, 10).Seq(), 0, double),
+		"MapConcurrentUnordered": stream.MapConcurrentUnordered(stream.Range(0, 10).Seq(), 0, double),
+	} {
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if got := len(stream.Collect(seq)); got != 10 {
+					t.Errorf("%s: got %d results, want 10", name, got)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestMapConcurrentPanic(t *testing.T) {
+	for _, mapConcurrent := range []func(iter.Seq[int], int, types.Function[int, int]) iter.Seq[int]{
+		stream.MapConcurrent[int, int],
+		stream.MapConcurrentUnordered[int, int],
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r != "boom" {
+					t.Errorf("recovered %v, want boom", r)
+				}
+			}()
+			stream.Count(mapConcurrent(stream.Range(0, 100).Seq(), 4, func(i int) int {
+				if i == 42 {
+					panic("boom")
+				}
+				return i
+			}))
+		}()
+	}
+}
+
+type event struct {
+	user string
+	seq  int
+}
+
+func ExamplePartitionBy() {
+	events := stream.Maps(stream.Range(0, 12), func(i int) event {
+		return event{user: string(rune('a' + i%3)), seq: i}
+	})
+	var mu sync.Mutex
+	handled := map[string][]int{}
+	err := stream.PartitionBy(context.Background(), events.Seq(), func(e event) string {
+		return e.user
+	}, 4, func(lane int, e event) {
+		mu.Lock()
+		defer mu.Unlock()
+		handled[e.user] = append(handled[e.user], e.seq)
+	})
+	fmt.Println(err)
+	for _, user := range []string{"a", "b", "c"} {
+		fmt.Println(user, handled[user])
+	}
+	// Output:
+	// <nil>
+	// a [0 3 6 9]
+	// b [1 4 7 10]
+	// c [2 5 8 11]
+}
+
+func ExampleMapWithState() {
+	events := stream.Maps(stream.Range(1, 9), func(i int) event {
+		return event{user: string(rune('a' + i%2)), seq: i}
+	})
+	// running sum of seq per user
+	sums := stream.MapWithState(context.Background(), events.Seq(), func(e event) string {
+		return e.user
+	}, 2, func(user string) int {
+		return 0
+	}, func(sum *int, e event) string {
+		*sum += e.seq
+		return fmt.Sprintf("%s=%d", e.user, *sum)
+	})
+	results := stream.Collect(sums)
+	slices.SortStableFunc(results, func(a, b string) int {
+		return strings.Compare(a[:1], b[:1]) // keep the order of each user
+	})
+	fmt.Println(results)
+	// Output:
+	// [a=2 a=6 a=12 a=20 b=1 b=4 b=9 b=16]
+}
+
+func TestPartitionByCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var handled atomic.Int32
+	err := stream.PartitionBy(ctx, stream.CountFrom(0).Seq(), func(i int) int {
+		return i
+	}, 3, func(lane int, i int) {
+		if handled.Add(1) == 100 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("got %v, want context.Canceled", err)
+	}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want boom", r)
+		}
+	}()
+	for range stream.MapWithState(context.Background(), stream.Range(0, 1000).Seq(), func(i int) int {
+		return i % 7
+	}, 3, func(int) int { return 0 }, func(_ *int, i int) int {
+		if i == 500 {
+			panic("boom")
+		}
+		return i
+	}) {
+	}
+}
+
+func TestPartitionByBlockedSource(t *testing.T) {
+	// 数据源阻塞在通道上时, ctx 取消后仍能及时返回
+	blocked := func(ch chan int) iter.Seq[int] {
+		return func(yield func(int) bool) {
+			for i := range ch {
+				if !yield(i) {
+					return
+				}
+			}
+		}
+	}
+	identity := func(i int) int { return i }
+	ch := make(chan int)
+	defer close(ch) // 让数据源的 goroutine 退出
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := stream.PartitionBy(ctx, blocked(ch), identity, 2, func(int, int) {}); err != context.DeadlineExceeded {
+		t.Errorf("got %v, want context.DeadlineExceeded", err)
+	}
+
+	ch2 := make(chan int)
+	defer close(ch2)
+	go func() { ch2 <- 1 }()
+	ctx, cancel = context.WithCancel(context.Background())
+	var results []int
+	for r := range stream.MapWithState(ctx, blocked(ch2), identity, 2, func(int) int { return 0 }, func(_ *int, i int) int {
+		cancel() // 处理第一个元素后取消, 此时数据源阻塞在下一个元素上
+		return i
+	}) {
+		results = append(results, r)
+	}
+	if len(results) > 1 {
+		t.Errorf("got %v, want at most one result", results)
+	}
+}
+
+func ExampleParallelReduce() {
+	floats := stream.Collect(stream.Map(stream.Range(1, 100001).Seq(), func(i int) float64 {
+		return 1 / float64(i)
+	}))
+	add := func(a, b float64) float64 { return a + b }
+	sum := stream.ParallelReduce(floats, 0.0, add, add, 8)
+	same := true
+	for range 20 {
+		same = same && stream.ParallelReduce(floats, 0.0, add, add, 8) == sum
+	}
+	fmt.Printf("%.6f %v\n", sum, same)
+	lengths := stream.ParallelReduce([]string{"a", "bb", "ccc"}, 0, func(n int, s string) int {
+		return n + len(s)
+	}, func(a, b int) int { return a + b }, 0)
+	fmt.Println(lengths, stream.ParallelReduce([]int{}, -1, func(a, b int) int { return a + b }, nil, 4))
+	// Output:
+	// 12.090146 true
+	// 6 -1
+}
+
+func ExampleParallelSorted() {
+	type pair struct{ key, seq int }
+	pairs := stream.Collect(stream.Map(stream.Range(0, 100000).Seq(), func(i int) pair {
+		return pair{key: (i * 7919) % 100, seq: i}
+	}))
+	sorted := stream.Collect(stream.ParallelSorted(slices.Values(pairs), func(a, b pair) int {
+		return a.key - b.key
+	}, 6))
+	stable := slices.IsSortedFunc(sorted, func(a, b pair) int {
+		if a.key != b.key {
+			return a.key - b.key
+		}
+		return a.seq - b.seq
+	})
+	fmt.Println(len(sorted), stable, sorted[0], sorted[len(sorted)-1])
+	// small Seqs are sorted sequentially
+	fmt.Println(stream.Collect(stream.ParallelSorted(slices.Values([]int{3, 1, 2}), cmp.Compare[int], 4)))
+	// Output:
+	// 100000 true {0 0} {99 99921}
+	// [1 2 3]
+}
+
+func ExamplePrefetch() {
+	var produced atomic.Int32
+	source := stream.Peek(stream.CountFrom(0).Seq(), func(int) {
+		produced.Add(1)
+	})
+	// the producer goroutine is stopped when Limit stops
+	first := stream.Collect(stream.Limit(stream.Prefetch(source, 4), 3))
+	fmt.Println(first, produced.Load() <= 3+4+1)
+
+	lines := func(yield func(string, error) bool) {
+		for _, line := range []string{"a", "b"} {
+			if !yield(line, nil) {
+				return
+			}
+		}
+		yield("", errors.New("disk error"))
+	}
+	for line, err := range stream.Prefetch2(lines, 2) {
+		if err != nil {
+			fmt.Println("error:", err)
+			break
+		}
+		fmt.Println(line)
+	}
+	// Output:
+	// [0 1 2] true
+	// a
+	// b
+	// error: disk error
+}
+
+func TestPrefetchPanic(t *testing.T) {
+	source := func(yield func(int) bool) {
+		yield(1)
+		panic("boom")
+	}
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recover() = %v, want boom", r)
+		}
+	}()
+	stream.Count(stream.Prefetch(source, 1))
+	t.Error("expected panic")
+}
+
+// timedEvents yields the values at the given offsets, advancing the fake clock before each one.
+func timedEvents(clk *clock.Fake, events ...any) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for i := 0; i < len(events); i += 2 {
+			clk.Set(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(events[i].(time.Duration)))
+			if !yield(events[i+1].(string)) {
+				return
+			}
+		}
+	}
+}
+
+func ExampleThrottle() {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	events := timedEvents(clk, 0*time.Millisecond, "a", 10*time.Millisecond, "b",
+		100*time.Millisecond, "c", 120*time.Millisecond, "d", 150*time.Millisecond, "e")
+	fmt.Println(stream.Collect(stream.Throttle(events, 50*time.Millisecond, clk)))
+	// Output:
+	// [a c e]
+}
+
+func ExampleDebounce() {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	events := timedEvents(clk, 0*time.Millisecond, "a", 10*time.Millisecond, "b",
+		100*time.Millisecond, "c", 120*time.Millisecond, "d", 200*time.Millisecond, "e", 210*time.Millisecond, "f")
+	fmt.Println(stream.Collect(stream.Debounce(events, 50*time.Millisecond, clk)))
+	// Output:
+	// [b d f]
+}
+
+func ExampleSample() {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	events := timedEvents(clk, 10*time.Millisecond, "a", 20*time.Millisecond, "b",
+		130*time.Millisecond, "c", 160*time.Millisecond, "d", 230*time.Millisecond, "e")
+	// periods: [0,50) [50,100) [100,150) [150,200) [200,250)
+	fmt.Println(stream.Collect(stream.Sample(events, 50*time.Millisecond, clk)))
+	// Output:
+	// [b c d]
+}
+
+func ExampleTimeout() {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	events := timedEvents(clk, 10*time.Millisecond, "a", 50*time.Millisecond, "b", 200*time.Millisecond, "c")
+	for e, err := range stream.Timeout(events, 100*time.Millisecond, clk) {
+		fmt.Println(e, err)
+	}
+	// Output:
+	// a <nil>
+	// b <nil>
+	//  stream: timeout
+}
+
+func ExampleBufferTime() {
+	clk := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	events := timedEvents(clk, 0*time.Millisecond, "a", 10*time.Millisecond, "b", 20*time.Millisecond, "c",
+		30*time.Millisecond, "d", 200*time.Millisecond, "e", 220*time.Millisecond, "f")
+	for batch := range stream.BufferTime(events, 100*time.Millisecond, 3, clk) {
+		fmt.Println(batch)
+	}
+	// Output:
+	// [a b c]
+	// [d]
+	// [e f]
+}
+
+func TestTimeoutChannelSource(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	ch := make(chan int)
+	source := func(yield func(int) bool) {
+		for v := range ch {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	received := make(chan struct{})
+	go func() {
+		ch <- 1
+		<-received
+		clk.BlockUntil(1) // the timer for the second element
+		clk.Advance(time.Second)
+	}()
+	var got []any
+	for v, err := range stream.Timeout(source, time.Second, clk) {
+		got = append(got, v, err)
+		if len(got) == 2 {
+			close(received)
+		}
+	}
+	if len(got) != 4 || got[0] != 1 || got[1] != nil || got[3] != stream.ErrTimeout {
+		t.Errorf("got %v", got)
+	}
+}
+
+func ExampleTumblingWindow() {
+	type metric struct {
+		at    time.Time
+		value int
+	}
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	metrics := []metric{
+		{base.Add(1 * time.Second), 1},
+		{base.Add(4 * time.Second), 2},
+		{base.Add(12 * time.Second), 3},
+		{base.Add(8 * time.Second), 4}, // out of order, but within 5s
+		{base.Add(31 * time.Second), 5},
+		{base.Add(2 * time.Second), 6}, // too late, dropped
+	}
+	windows := stream.TumblingWindow(slices.Values(metrics), 10*time.Second, func(m metric) time.Time {
+		return m.at
+	}, stream.WithMaxOutOfOrderness(5*time.Second))
+	sums := stream.Map(windows, func(w types.Window[metric]) string {
+		sum := stream.ReduceFrom(stream.Map(slices.Values(w.Items), func(m metric) int { return m.value }), 0,
+			func(a, b int) int { return a + b })
+		return fmt.Sprintf("[%s, %s) sum=%d", w.Start.Format("04:05"), w.End.Format("04:05"), sum)
+	})
+	for s := range sums {
+		fmt.Println(s)
+	}
+	// Output:
+	// [00:00, 00:10) sum=7
+	// [00:10, 00:20) sum=3
+	// [00:30, 00:40) sum=5
+}
+
+func ExampleSlidingWindow() {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seconds := []int{0, 3, 6, 9}
+	for w := range stream.SlidingWindow(slices.Values(seconds), 6*time.Second, 3*time.Second, func(s int) time.Time {
+		return base.Add(time.Duration(s) * time.Second)
+	}) {
+		fmt.Println(w.Start.Second(), w.End.Second(), w.Items)
+	}
+	// Output:
+	// 57 3 [0]
+	// 0 6 [0 3]
+	// 3 9 [3 6]
+	// 6 12 [6 9]
+	// 9 15 [9]
+}
+
+func ExampleSessionWindow() {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clicks := []int{0, 2, 30, 5, 33, 60}
+	for w := range stream.SessionWindow(slices.Values(clicks), 10*time.Second, func(s int) time.Time {
+		return base.Add(time.Duration(s) * time.Second)
+	}, stream.WithMaxOutOfOrderness(30*time.Second)) {
+		fmt.Println(w.Start.Second(), w.End.Second(), w.Items)
+	}
+	// Output:
+	// 0 15 [0 2 5]
+	// 30 43 [30 33]
+	// 0 10 [60]
+}
+
+func TestWindowInvalidArguments(t *testing.T) {
+	ts := func(i int) time.Time { return time.Unix(int64(i), 0) }
+	tests := []struct {
+		name string
+		f    func() iter.Seq[types.Window[int]]
+		want string
+	}{
+		{"tumbling size 0", func() iter.Seq[types.Window[int]] {
+			return stream.TumblingWindow(stream.Of(1).Seq(), 0, ts)
+		}, "stream: TumblingWindow size must be positive"},
+		{"sliding size 0", func() iter.Seq[types.Window[int]] {
+			return stream.SlidingWindow(stream.Of(1).Seq(), 0, time.Second, ts)
+		}, "stream: SlidingWindow size and slide must be positive"},
+		{"sliding slide 0", func() iter.Seq[types.Window[int]] {
+			return stream.SlidingWindow(stream.Of(1).Seq(), time.Second, 0, ts)
+		}, "stream: SlidingWindow size and slide must be positive"},
+		{"sliding negative slide", func() iter.Seq[types.Window[int]] {
+			return stream.SlidingWindow(stream.Of(1).Seq(), time.Second, -time.Second, ts)
+		}, "stream: SlidingWindow size and slide must be positive"},
+		{"session gap 0", func() iter.Seq[types.Window[int]] {
+			return stream.SessionWindow(stream.Of(1).Seq(), 0, ts)
+		}, "stream: SessionWindow gap must be positive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); r != tt.want {
+					t.Errorf("recovered %v, want %s", r, tt.want)
+				}
+			}()
+			tt.f()
+		})
+	}
+}
+
+func ExampleWithAllowedLateness() {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	seconds := []int{1, 2, 11, 3, 25, 4}
+	for w := range stream.TumblingWindow(slices.Values(seconds), 10*time.Second, func(s int) time.Time {
+		return base.Add(time.Duration(s) * time.Second)
+	}, stream.WithAllowedLateness(5*time.Second)) {
+		fmt.Println(w.Start.Second(), w.Items)
+	}
+	// Output:
+	// 0 [1 2]
+	// 0 [1 2 3]
+	// 10 [11]
+	// 20 [25]
+}
+
+func ExampleRateLimit() {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	go func() {
+		for {
+			clk.BlockUntil(1) // a token is being waited for
+			clk.Advance(100 * time.Millisecond)
+		}
+	}()
+	// 10 per second, burst 3
+	for i := range stream.RateLimit(context.Background(), stream.Range(0, 6).Seq(), 10, 3, clk) {
+		fmt.Println(i, clk.Now().Sub(start))
+	}
+	// Output:
+	// 0 0s
+	// 1 0s
+	// 2 0s
+	// 3 100ms
+	// 4 200ms
+	// 5 300ms
+}
+
+func ExampleRateLimitBy() {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	go func() {
+		for {
+			clk.BlockUntil(1)
+			clk.Advance(time.Second)
+		}
+	}()
+	requests := slices.Values([]string{"alice", "bob", "alice", "bob", "carol"})
+	for user := range stream.RateLimitBy(context.Background(), requests, func(s string) string {
+		return s
+	}, 1, 1, clk) {
+		fmt.Println(user, clk.Now().Sub(start))
+	}
+	// Output:
+	// alice 0s
+	// bob 0s
+	// alice 1s
+	// bob 1s
+	// carol 1s
+}
+
+func TestRateLimitCancel(t *testing.T) {
+	clk := clock.NewFake(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		clk.BlockUntil(1) // waiting for the second token
+		cancel()
+	}()
+	got := stream.Collect(stream.RateLimit(ctx, stream.Range(0, 10).Seq(), 1, 1, clk))
+	if !slices.Equal(got, []int{0}) {
+		t.Errorf("got %v, want [0]", got)
+	}
+}
+
+// visit is a request of key, which arrives after the clock is idle for a while.
+type visit struct {
+	key  string
+	idle time.Duration
+}
+
+func TestRateLimitByIdleCycles(t *testing.T) {
+	start := time.Now()
+	clk := clock.NewFake(start)
+	go func() {
+		for {
+			clk.BlockUntil(1)
+			clk.Advance(time.Second)
+		}
+	}()
+	// 1 per second, burst 2. In each cycle "hot" spends its burst and waits 1s for the third token,
+	// while new keys sweep the buckets: the empty "hot" bucket must be kept,
+	// and it must be released (or still full) after idling 10s.
+	const cycles = 5
+	var visits []visit
+	for i := range cycles {
+		visits = append(visits, visit{key: "hot", idle: 10 * time.Second}, visit{key: "hot"})
+		for j := range 3 {
+			visits = append(visits, visit{key: fmt.Sprintf("cold-%d-%d", i, j)})
+		}
+		visits = append(visits, visit{key: "hot"})
+	}
+	arrivals := func(yield func(visit) bool) {
+		for _, v := range visits {
+			clk.Advance(v.idle)
+			if !yield(v) {
+				return
+			}
+		}
+	}
+	got := stream.Collect(stream.RateLimitBy(context.Background(), arrivals, func(v visit) string {
+		return v.key
+	}, 1, 2, clk))
+	if len(got) != len(visits) {
+		t.Errorf("got %d visits, want %d", len(got), len(visits))
+	}
+	if got, want := clk.Now().Sub(start), cycles*11*time.Second; got != want {
+		t.Errorf("elapsed %v, want %v", got, want)
+	}
+}
+
+func TestRateLimitInvalid(t *testing.T) {
+	for _, c := range []struct {
+		rate  float64
+		burst int
+	}{{0, 1}, {-1, 1}, {math.NaN(), 1}, {1, 0}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("RateLimit(%v, %d): expected panic", c.rate, c.burst)
+				}
+			}()
+			stream.RateLimit(context.Background(), stream.Range(0, 1).Seq(), c.rate, c.burst, clock.NewFake(time.Now()))
+		}()
+	}
+}
+
+func ExampleMapRetry() {
+	failures := map[string]int{"a": 2, "b": 5} // how many times each element fails
+	var slept []time.Duration
+	policy := stream.RetryPolicy[string]{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     150 * time.Millisecond,
+		Jitter:         0.5,
+		Rand:           func() float64 { return 0.5 },
+		Sleep: func(d time.Duration) {
+			slept = append(slept, d)
+		},
+		DeadLetter: func(e string, err error) {
+			fmt.Println("dead letter:", e, err)
+		},
+	}
+	result := stream.MapRetry(slices.Values([]string{"a", "b", "c"}), func(s string) (string, error) {
+		if failures[s] > 0 {
+			failures[s]--
+			return "", errors.New("unavailable")
+		}
+		return strings.ToUpper(s), nil
+	}, policy)
+	fmt.Println(stream.Collect(result), slept)
+	// Output:
+	// dead letter: b unavailable
+	// [A C] [75ms 112.5ms 75ms 112.5ms]
+}
+
+func ExampleMapOrElse() {
+	result := stream.MapOrElse(slices.Values([]string{"1", "x", "3"}), strconv.Atoi, func(s string, err error) int {
+		return -1
+	})
+	fmt.Println(stream.Collect(result))
+	// Output:
+	// [1 -1 3]
+}
+
+func TestMapRetryLargeAttempts(t *testing.T) {
+	// the backoff overflows float64 after about 1000 doublings, it must stay a valid positive duration
+	for _, initial := range []time.Duration{time.Second, 0} {
+		var slept []time.Duration
+		calls := 0
+		stream.Count(stream.MapRetry(slices.Values([]int{1}), func(e int) (int, error) {
+			if calls++; calls < 2000 {
+				return 0, errors.New("unavailable")
+			}
+			return e, nil
+		}, stream.RetryPolicy[int]{
+			MaxAttempts:    2000,
+			InitialBackoff: initial,
+			Sleep:          func(d time.Duration) { slept = append(slept, d) },
+		}))
+		for i, d := range slept {
+			if d < 0 || i > 0 && d < slept[i-1] || initial == 0 && d != 0 {
+				t.Fatalf("initial %v: attempt %d slept %v after %v", initial, i+1, d, slept[max(i-1, 0)])
+			}
+		}
+	}
+}
+
+func TestMapRetryPanic(t *testing.T) {
+	errPermanent := errors.New("permanent")
+	calls := 0
+	defer func() {
+		err, ok := recover().(*stream.RetryError)
+		if !ok || err.Attempts != 1 || !errors.Is(err, errPermanent) || calls != 1 {
+			t.Errorf("recover() = %v, calls = %d", err, calls)
+		}
+	}()
+	stream.Count(stream.MapRetry(slices.Values([]int{1}), func(int) (int, error) {
+		calls++
+		return 0, errPermanent
+	}, stream.RetryPolicy[int]{
+		MaxAttempts: 5,
+		Sleep:       func(time.Duration) {},
+		IsRetryable: func(err error) bool { return err != errPermanent },
+	}))
+	t.Error("expected panic")
+}
+
+type customer struct {
+	id   int
+	name string
+}
+
+type order struct {
+	id       int
+	customer int
+}
+
+var (
+	customers = []customer{{1, "alice"}, {2, "bob"}, {3, "carol"}}
+	orders    = []order{{100, 1}, {101, 3}, {102, 1}, {103, 4}, {104, 2}}
+)
+
+func customerID(c customer) int { return c.id }
+func orderCustomer(o order) int { return o.customer }
+
+func ExampleJoin() {
+	// customers is the smaller side, so the output follows the order of orders
+	for p := range stream.Join(slices.Values(orders), slices.Values(customers), orderCustomer, customerID) {
+		fmt.Println(p.First.id, p.Second.name)
+	}
+	// Output:
+	// 100 alice
+	// 101 carol
+	// 102 alice
+	// 104 bob
+}
+
+func ExampleLeftJoin() {
+	for p := range stream.LeftJoin(slices.Values(orders), slices.Values(customers), orderCustomer, customerID) {
+		fmt.Println(p.First.id, p.Second.Or(customer{name: "-"}).name)
+	}
+	// Output:
+	// 100 alice
+	// 101 carol
+	// 102 alice
+	// 103 -
+	// 104 bob
+}
+
+func TestLeftJoinOrder(t *testing.T) {
+	name := func(p types.Pair[customer, types.Optional[order]]) string {
+		return fmt.Sprint(p.First.name, p.Second.Or(order{id: -1}).id)
+	}
+	// customers is the smaller side: matched pairs follow the order of orders, then the customers without order
+	few := []order{{100, 3}, {101, 1}, {102, 5}}
+	got := stream.Collect(stream.Map(stream.LeftJoin(slices.Values(customers), slices.Values(few), customerID, orderCustomer), name))
+	if want := []string{"carol100", "alice101", "bob-1"}; !slices.Equal(got, want) {
+		t.Errorf("left smaller: got %v, want %v", got, want)
+	}
+	// orders is the smaller side: the output follows the order of customers
+	got = stream.Collect(stream.Map(stream.LeftJoin(slices.Values(customers), slices.Values(few[:1]), customerID, orderCustomer), name))
+	if want := []string{"alice-1", "bob-1", "carol100"}; !slices.Equal(got, want) {
+		t.Errorf("right smaller: got %v, want %v", got, want)
+	}
+}
+
+func ExampleFullOuterJoin() {
+	all := []customer{{1, "alice"}, {5, "eve"}}
+	for p := range stream.FullOuterJoin(slices.Values(all), slices.Values(orders), customerID, orderCustomer) {
+		fmt.Println(p.First.Or(customer{name: "-"}).name, p.Second.Or(order{id: -1}).id)
+	}
+	// Output:
+	// alice 100
+	// - 101
+	// alice 102
+	// - 103
+	// - 104
+	// eve -1
+}
+
+func ExampleSemiJoin() {
+	buyers := stream.SemiJoin(slices.Values(customers), slices.Values(orders[:2]), customerID, orderCustomer)
+	others := stream.AntiJoin(slices.Values(customers), slices.Values(orders[:2]), customerID, orderCustomer)
+	fmt.Println(stream.Collect(buyers), stream.Collect(others))
+	// Output:
+	// [{1 alice} {3 carol}] [{2 bob}]
+}
+
+func ExampleSortedMergeJoin() {
+	left := slices.Values([]int{1, 2, 2, 4, 5})
+	right := slices.Values([]string{"1a", "2a", "2b", "3a", "5a"})
+	for p := range stream.SortedMergeJoin(left, right, func(i int) int { return i }, func(s string) int {
+		return int(s[0] - '0')
+	}, cmp.Compare[int]) {
+		fmt.Println(p.First, p.Second)
+	}
+	// Output:
+	// 1 1a
+	// 2 2a
+	// 2 2b
+	// 2 2a
+	// 2 2b
+	// 5 5a
+}
+
+func TestJoinBuildSide(t *testing.T) {
+	// the smaller side is built whichever argument it is, the infinite side is not exhausted
+	small := slices.Values([]int{1, 2})
+	infinite := stream.CountFrom(0).Seq()
+	id := func(i int) int { return i }
+	got := stream.Collect(stream.Limit(stream.Join(infinite, small, id, id), 2))
+	want := []types.Pair[int, int]{{First: 1, Second: 1}, {First: 2, Second: 2}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	got = stream.Collect(stream.Limit(stream.Join(small, infinite, id, id), 2))
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func ExampleAsOfJoin() {
+	type quote struct {
+		symbol string
+		at     int // seconds
+		price  float64
+	}
+	type trade struct {
+		symbol string
+		at     int
+		qty    int
+	}
+	base := time.Date(2024, 1, 1, 9, 30, 0, 0, time.UTC)
+	quotes := []quote{{"AAPL", 0, 100}, {"MSFT", 1, 300}, {"AAPL", 5, 101}, {"AAPL", 10, 102}}
+	trades := []trade{{"AAPL", 3, 10}, {"MSFT", 5, 20}, {"AAPL", 10, 30}, {"GOOG", 11, 40}, {"MSFT", 20, 50}}
+	joined := stream.AsOfJoin(slices.Values(trades), slices.Values(quotes),
+		func(t trade) time.Time { return base.Add(time.Duration(t.at) * time.Second) },
+		func(q quote) time.Time { return base.Add(time.Duration(q.at) * time.Second) },
+		func(t trade) string { return t.symbol },
+		func(q quote) string { return q.symbol },
+		10*time.Second)
+	for p := range joined {
+		price := p.Second.Or(quote{price: -1}).price
+		fmt.Println(p.First.symbol, p.First.at, price)
+	}
+	// Output:
+	// AAPL 3 100
+	// MSFT 5 300
+	// AAPL 10 102
+	// GOOG 11 -1
+	// MSFT 20 -1
+}
+
+func ExampleMergeSorted() {
+	shard1 := slices.Values([]int{1, 4, 7})
+	shard2 := slices.Values([]int{2, 4, 8, 9})
+	shard3 := slices.Values([]int{0, 5})
+	fmt.Println(stream.Collect(stream.MergeSorted(cmp.Compare[int], shard1, shard2, shard3)))
+	// lazy: only the heads are pulled
+	fmt.Println(stream.Collect(stream.Limit(stream.MergeSorted(cmp.Compare[int],
+		stream.CountFrom(0).Seq(), stream.CountFrom(10).Seq()), 3)))
+	// Output:
+	// [0 1 2 4 4 5 7 8 9]
+	// [0 1 2]
+}
+
+func ExampleUnionSorted() {
+	a := slices.Values([]int{1, 2, 2, 3, 5})
+	b := slices.Values([]int{2, 3, 4})
+	c := slices.Values([]int{3, 5, 6})
+	compare := cmp.Compare[int]
+	fmt.Println(stream.Collect(stream.UnionSorted(compare, a, b, c)))
+	fmt.Println(stream.Collect(stream.IntersectSorted(compare, a, b, c)))
+	fmt.Println(stream.Collect(stream.IntersectSorted(compare, a, b)))
+	fmt.Println(stream.Collect(stream.ExceptSorted(compare, a, b)))
+	fmt.Println(stream.Collect(stream.ExceptSorted(compare, a, b, c)))
+	// Output:
+	// [1 2 3 4 5 6]
+	// [3]
+	// [2 3]
+	// [1 5]
+	// [1]
+}
+
+func ExampleUnion() {
+	a := slices.Values([]string{"apple", "Banana", "cherry", "apple"})
+	b := slices.Values([]string{"banana", "date", "Cherry", "elderberry"})
+	lower := strings.ToLower
+	fmt.Println(stream.Collect(stream.Union(a, b, lower)))
+	fmt.Println(stream.Collect(stream.Intersect(a, b, lower)))
+	fmt.Println(stream.Collect(stream.Except(a, b, lower)))
+	fmt.Println(stream.Collect(stream.SymmetricDifference(a, b, lower)))
+	// Output:
+	// [apple Banana cherry date elderberry]
+	// [Banana cherry]
+	// [apple]
+	// [apple date elderberry]
+}
+
+func ExampleDiff() {
+	type row struct {
+		id    int
+		email string
+	}
+	yesterday := []row{{1, "a@x.com"}, {2, "b@x.com"}, {3, "c@x.com"}}
+	today := []row{{3, "c@y.com"}, {1, "a@x.com"}, {4, "d@x.com"}}
+	id := func(r row) int { return r.id }
+	equal := func(a, b row) bool { return a == b }
+	show := func(c stream.Change[row]) {
+		fmt.Println(c.Kind, c.Old.Or(row{email: "-"}).email, c.New.Or(row{email: "-"}).email)
+	}
+	stream.ForEach(stream.Diff(slices.Values(yesterday), slices.Values(today), id, equal), show)
+	fmt.Println("sorted:")
+	slices.SortFunc(today, func(a, b row) int { return a.id - b.id })
+	stream.ForEach(stream.DiffSorted(slices.Values(yesterday), slices.Values(today), id, cmp.Compare[int], equal), show)
+	// Output:
+	// Modified c@x.com c@y.com
+	// Unchanged a@x.com a@x.com
+	// Added - d@x.com
+	// Removed b@x.com -
+	// sorted:
+	// Unchanged a@x.com a@x.com
+	// Removed b@x.com -
+	// Modified c@x.com c@y.com
+	// Added - d@x.com
+}
+
+func ExampleReservoirSample() {
+	// 个数未知的 Seq
+	i := 0
+	generated := stream.Generate(func() int { i++; return i }).Limit(1000).Seq()
+	fmt.Println(len(stream.ReservoirSample(generated, 5, rand.New(rand.NewPCG(1, 2)))))
+	fmt.Println(stream.ReservoirSample(stream.Range(0, 3).Seq(), 5, nil))
+
+	// 每个元素被选中的次数大致相同
+	counts := make([]int, 10)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for n := 0; n < 10000; n++ {
+		for _, e := range stream.ReservoirSample(stream.Range(0, 10).Seq(), 3, rng) {
+			counts[e]++
+		}
+	}
+	fmt.Println(stream.Of(counts...).AllMatch(func(c int) bool { return c > 2800 && c < 3200 }))
+	// Output:
+	// 5
+	// [0 1 2]
+	// true
+}
+
+func ExampleSampleFraction() {
+	count := stream.Count(stream.SampleFraction(stream.Range(0, 10000).Seq(), 0.1, rand.New(rand.NewPCG(1, 2))))
+	fmt.Println(count > 900 && count < 1100)
+	fmt.Println(stream.Count(stream.SampleFraction(stream.Range(0, 10).Seq(), 0, nil)))
+	fmt.Println(stream.Count(stream.SampleFraction(stream.Range(0, 10).Seq(), 1, nil)))
+	// Output:
+	// true
+	// 0
+	// 10
+}
+
+func ExampleShuffled() {
+	shuffled := stream.Collect(stream.Shuffled(stream.Range(0, 10).Seq(), rand.New(rand.NewPCG(1, 2))))
+	fmt.Println(len(shuffled))
+	slices.Sort(shuffled)
+	fmt.Println(shuffled)
+	fmt.Println(stream.Collect(stream.Shuffled(stream.Of[int]().Seq(), nil)))
+	// Output:
+	// 10
+	// [0 1 2 3 4 5 6 7 8 9]
+	// []
+}
+
+func ExampleWeightedSample() {
+	// 权重为 0 的元素不会被选中, 权重越大越容易被选中
+	weight := func(i int) float64 { return float64(i) }
+	counts := make([]int, 4)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for n := 0; n < 10000; n++ {
+		for _, e := range stream.WeightedSample(stream.Range(0, 4).Seq(), 1, weight, rng) {
+			counts[e]++
+		}
+	}
+	fmt.Println(counts[0], counts[1] < counts[2], counts[2] < counts[3])
+	fmt.Println(len(stream.WeightedSample(stream.Range(0, 4).Seq(), 5, weight, nil)))
+	// Output:
+	// 0 true true
+	// 3
+}
+
+func ExampleDistinctWindow() {
+	// 只记住最近 2 个不同的 key: 重复的 1 会刷新 key, 2 在 1, 3 之后再次出现时已被忘记
+	id := func(i int) int { return i }
+	fmt.Println(stream.Collect(stream.DistinctWindow(stream.Of(1, 2, 1, 3, 1, 2).Seq(), id, 2)))
+	// Output:
+	// [1 2 3 2]
+}
+
+func ExampleDistinctApprox() {
+	// 不会漏掉重复元素, 不同元素有不超过 1% 的概率被误判为重复
+	count := stream.Count(stream.DistinctApprox(stream.Range(0, 2000).Seq(), func(i int) int { return i % 1000 }, 1000, 0.01))
+	fmt.Println(count >= 990 && count <= 1000)
+	// Output:
+	// true
+}
+
+func ExampleDistinctWithin() {
+	type message struct {
+		id    int
+		delay time.Duration // 与上一条消息的间隔
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := clock.NewFake(start)
+	messages := stream.Of(message{1, 0}, message{2, time.Second}, message{1, time.Second}, message{2, time.Second}, message{1, time.Second}).
+		Peek(func(m message) { clk.Advance(m.delay) }).Seq()
+	for m := range stream.DistinctWithin(messages, func(m message) int { return m.id }, 3*time.Second, clk) {
+		fmt.Println(clk.Now().Sub(start), m.id)
+	}
+	// Output:
+	// 0s 1
+	// 1s 2
+	// 4s 1
+}
+
+func ExampleCountBy() {
+	words := stream.Of("apple", "banana", "avocado", "cherry", "blueberry", "apricot").Seq()
+	counts := stream.CountBy(words, func(s string) byte { return s[0] })
+	fmt.Println(counts['a'], counts['b'], counts['c'], counts['d'])
+	// Output:
+	// 3 2 1 0
+}
+
+func ExampleMostCommon() {
+	words := stream.Of(strings.Fields("the cat and the dog and the bird saw a cat")...).Seq()
+	identity := func(s string) string { return s }
+	fmt.Println(stream.MostCommon(words, identity, 3))
+	// 次数相同时先出现的在前
+	fmt.Println(stream.MostCommon(words, identity, 5))
+	fmt.Println(stream.MostCommon(words, identity, 0))
+	// Output:
+	// [{the 3} {cat 2} {and 2}]
+	// [{the 3} {cat 2} {and 2} {dog 1} {bird 1}]
+	// []
+}
+
+func ExampleHistogram() {
+	latencies := stream.Of(3, 7, 12, 25, 40, 90, 150, 1000).Seq()
+	toFloat := func(i int) float64 { return float64(i) }
+	for _, b := range stream.Histogram(latencies, toFloat, stream.ExponentialBuckets(10, 10, 3)) {
+		fmt.Println(b.Low, b.High, b.Count)
+	}
+	values := stream.Of(0.5, 1, 1.5, 2.5, math.NaN()).Seq()
+	fmt.Println(stream.Histogram(values, func(f float64) float64 { return f }, stream.LinearBuckets(0, 1, 3)))
+	// Output:
+	// -Inf 10 2
+	// 10 100 4
+	// 100 1000 1
+	// 1000 +Inf 1
+	// [{-Inf 0 0} {0 1 1} {1 2 2} {2 +Inf 1}]
+}
+
+func ExampleRollingSum() {
+	fmt.Println(stream.Collect(stream.RollingSum(stream.Of(1, 2, 3, 4, 5).Seq(), 3)))
+	// Output:
+	// [1 3 6 9 12]
+}
+
+func ExampleMovingAverage() {
+	fmt.Println(stream.Collect(stream.MovingAverage(stream.Of(1, 2, 3, 4, 5).Seq(), 2)))
+	fmt.Println(stream.Collect(stream.MovingAverage(stream.Of[uint8](200, 100).Seq(), 0)))
+	// Output:
+	// [1 1.5 2.5 3.5 4.5]
+	// [200 100]
+}
+
+func ExampleRollingMinMax() {
+	fmt.Println(stream.Collect(stream.RollingMinMax(stream.Of(3, 1, 4, 1, 5, 9, 2, 6).Seq(), 3)))
+	// Output:
+	// [{3 3} {1 3} {1 4} {1 4} {1 5} {1 9} {2 9} {2 9}]
+}
+
+func ExampleEWMA() {
+	latencies := stream.Of(10.0, 20, 20, 20, 100).Seq()
+	fmt.Println(stream.Collect(stream.EWMA(latencies, 0.5)))
+	// Output:
+	// [10 15 17.5 18.75 59.375]
+}
+
+func TestRollingSumFloat(t *testing.T) {
+	inf := math.Inf(1)
+	for _, c := range []struct {
+		values []float64
+		n      int
+		want   []float64
+	}{
+		{[]float64{1, inf, 1, 1, 1}, 2, []float64{1, inf, inf, 2, 2}},              // an evicted Inf does not leave NaN
+		{[]float64{1, inf, -inf, 1, 1}, 2, []float64{1, inf, math.NaN(), -inf, 2}}, // Inf - Inf is NaN while both are in the window
+		{[]float64{1, math.NaN(), 1, 1}, 2, []float64{1, math.NaN(), math.NaN(), 2}},
+		{[]float64{1e17, 1, 1, 1, 1}, 3, []float64{1e17, 1e17 + 1, 1e17 + 2, 3, 3}}, // no cancellation after 1e17 is evicted
+		{[]float64{math.MaxFloat64, math.MaxFloat64, 1, 1}, 2, []float64{math.MaxFloat64, inf, math.MaxFloat64 + 1, 2}},
+	} {
+		got := stream.Collect(stream.RollingSum(slices.Values(c.values), c.n))
+		if !slices.EqualFunc(got, c.want, func(a, b float64) bool { return a == b || math.IsNaN(a) && math.IsNaN(b) }) {
+			t.Errorf("RollingSum(%v, %d) = %v, want %v", c.values, c.n, got, c.want)
+		}
+	}
+}
+
+func TestMovingAverageExact(t *testing.T) {
+	if got, want := stream.Collect(stream.MovingAverage(slices.Values([]float64{1e17, 1, 1, 1}), 1)), []float64{1e17, 1, 1, 1}; !slices.Equal(got, want) {
+		t.Errorf("floats: got %v, want %v", got, want)
+	}
+	// integers are summed exactly: no precision is lost above 2^53, and the sum does not overflow
+	if got, want := stream.Collect(stream.MovingAverage(slices.Values([]int64{1 << 60, 1, 3}), 2)), []float64{1 << 60, (1<<60 + 1) / 2.0, 2}; !slices.Equal(got, want) {
+		t.Errorf("int64: got %v, want %v", got, want)
+	}
+	if got, want := stream.Collect(stream.MovingAverage(slices.Values([]int64{math.MaxInt64, math.MaxInt64, -1, -2}), 2)), []float64{math.MaxInt64, math.MaxInt64, math.MaxInt64 / 2, -1.5}; !slices.Equal(got, want) {
+		t.Errorf("int64 overflow: got %v, want %v", got, want)
+	}
+	if got, want := stream.Collect(stream.MovingAverage(slices.Values([]uint64{math.MaxUint64, math.MaxUint64, 1}), 2)), []float64{math.MaxUint64, math.MaxUint64, math.MaxUint64 / 2}; !slices.Equal(got, want) {
+		t.Errorf("uint64 overflow: got %v, want %v", got, want)
+	}
+	if got, want := stream.Collect(stream.MovingAverage(slices.Values([]uint8{200, 100}), 2)), []float64{200, 150}; !slices.Equal(got, want) {
+		t.Errorf("uint8 overflow: got %v, want %v", got, want)
+	}
+	r := rand.New(rand.NewPCG(1, 2))
+	ints, floats := make([]int8, 1000), make([]float64, 1000)
+	for i := range ints {
+		ints[i] = int8(r.IntN(256) - 128)
+		floats[i] = float64(ints[i]) / 4 // exactly representable, so the float sums are exact too
+	}
+	for _, n := range []int{1, 3, 50} {
+		averages := stream.Collect(stream.MovingAverage(slices.Values(ints), n))
+		floatAverages := stream.Collect(stream.MovingAverage(slices.Values(floats), n))
+		for i := range ints {
+			sum := 0
+			for _, v := range ints[max(0, i-n+1) : i+1] {
+				sum += int(v)
+			}
+			want := float64(sum) / float64(min(i+1, n))
+			if averages[i] != want || floatAverages[i] != want/4 {
+				t.Fatalf("n=%d i=%d: got %v and %v, want %v", n, i, averages[i], floatAverages[i], want)
+			}
+		}
+	}
+}
+
+func TestRollingMinMax(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	values := make([]int, 1000)
+	for i := range values {
+		values[i] = r.IntN(100)
+	}
+	for _, n := range []int{1, 2, 7, 50} {
+		i := 0
+		for p := range stream.RollingMinMax(slices.Values(values), n) {
+			window := values[max(0, i-n+1) : i+1]
+			if p.First != slices.Min(window) || p.Second != slices.Max(window) {
+				t.Fatalf("n=%d i=%d: got %v, want %v~%v", n, i, p, slices.Min(window), slices.Max(window))
+			}
+			i++
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package stream
+
+import (
+	"container/heap"
+	"fmt"
+	"iter"
+	"math"
+	"sort"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// CountBy counts the elements by key.
+// 按 key 统计元素个数
+func CountBy[T any, K comparable](it iter.Seq[T], key types.Function[T, K]) map[K]int64 {
+	return ReduceWith(it, make(map[K]int64), func(acc map[K]int64, e T) map[K]int64 {
+		acc[key(e)]++
+		return acc
+	})
+}
+
+// MostCommon returns the n most common keys with their counts, sorted by count descending,
+// keys with the same count are in the encounter order. A heap of size n selects the top n.
+// 出现次数最多的 n 个 key 及其次数, 按次数降序排列, 次数相同时先出现的 key 在前. 使用大小为 n 的堆选出前 n 个
+func MostCommon[T any, K comparable](it iter.Seq[T], key types.Function[T, K], n int) []types.Pair[K, int64] {
+	counts := ReduceWith(it, make(map[K]*common[K]), func(acc map[K]*common[K], e T) map[K]*common[K] {
+		k := key(e)
+		if c, ok := acc[k]; ok {
+			c.count++
+		} else {
+			acc[k] = &common[K]{key: k, count: 1, order: len(acc)}
+		}
+		return acc
+	})
+	h := &commonHeap[K]{}
+	for _, c := range counts {
+		if n <= 0 {
+			break
+		}
+		if h.Len() < n {
+			heap.Push(h, *c)
+		} else if h.less((*h)[0], *c) {
+			(*h)[0] = *c
+			heap.Fix(h, 0)
+		}
+	}
+	result := make([]types.Pair[K, int64], h.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		c := heap.Pop(h).(common[K])
+		result[i] = types.Pair[K, int64]{First: c.key, Second: c.count}
+	}
+	return result
+}
+
+type common[K any] struct {
+	key   K
+	count int64
+	order int // 第一次出现的顺序
+}
+
+// commonHeap 最小堆, 堆顶是次数最少(次数相同时最后出现)的 key
+type commonHeap[K any] []common[K]
+
+// less a 排在 b 之后
+func (h commonHeap[K]) less(a, b common[K]) bool {
+	if a.count != b.count {
+		return a.count < b.count
+	}
+	return a.order > b.order
+}
+func (h commonHeap[K]) Len() int           { return len(h) }
+func (h commonHeap[K]) Less(i, j int) bool { return h.less(h[i], h[j]) }
+func (h commonHeap[K]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *commonHeap[K]) Push(x any)        { *h = append(*h, x.(common[K])) }
+func (h *commonHeap[K]) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Bucket is a histogram bucket [Low, High) and the number of values in it.
+// 直方图的桶 [Low, High) 及其中值的个数
+type Bucket struct {
+	Low   float64
+	High  float64
+	Count int64
+}
+
+// LinearBuckets returns count boundaries, starting at start, each width apart.
+// 返回 count 个等差的边界 start, start+width, ...
+func LinearBuckets(start, width float64, count int) []float64 {
+	bounds := make([]float64, 0, count)
+	for i := range count {
+		bounds = append(bounds, start+float64(i)*width)
+	}
+	return bounds
+}
+
+// ExponentialBuckets returns count boundaries, starting at start, each factor times the previous.
+// 返回 count 个等比的边界 start, start*factor, ...
+func ExponentialBuckets(start, factor float64, count int) []float64 {
+	bounds := make([]float64, 0, count)
+	for range count {
+		bounds = append(bounds, start)
+		start *= factor
+	}
+	return bounds
+}
+
+// Histogram counts the values in the buckets split by the strictly increasing bounds,
+// which can be made by LinearBuckets or ExponentialBuckets.
+// It returns len(bounds)+1 buckets, the first is [-Inf, bounds[0]), the last is [bounds[len-1], +Inf).
+// NaN is ignored. It panics if bounds are not strictly increasing.
+// 直方图: 按严格递增的边界 bounds 统计 value 落在每个桶中的个数, 边界可以使用 LinearBuckets, ExponentialBuckets 生成.
+// 返回 len(bounds)+1 个桶, 第一个桶为 [-Inf, bounds[0]), 最后一个为 [bounds[len-1], +Inf). 忽略 NaN.
+// bounds 不是严格递增时 panic
+func Histogram[T any](it iter.Seq[T], value types.Function[T, float64], bounds []float64) []Bucket {
+	buckets := make([]Bucket, len(bounds)+1)
+	low := math.Inf(-1)
+	for i, high := range bounds {
+		if !(high > low) {
+			panic(fmt.Sprintf("stream: histogram bounds must be strictly increasing: %v", bounds))
+		}
+		buckets[i].Low, buckets[i].High = low, high
+		low = high
+	}
+	buckets[len(bounds)].Low, buckets[len(bounds)].High = low, math.Inf(1)
+	return ReduceWith(it, buckets, func(acc []Bucket, e T) []Bucket {
+		if v := value(e); !math.IsNaN(v) {
+			// 第一个边界大于 v 的桶
+			acc[sort.Search(len(bounds), func(i int) bool { return bounds[i] > v })].Count++
+		}
+		return acc
+	})
+}
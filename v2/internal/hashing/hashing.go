@@ -0,0 +1,139 @@
+// Package hashing hashes comparable values to uint64,
+// used to partition elements and build probabilistic data structures.
+// 将可比较的值哈希为 uint64, 用于分区和概率数据结构
+package hashing
+
+import (
+	"math"
+	"reflect"
+)
+
+// Of returns a well distributed 64-bit hash of k.
+// Equal (==) values have equal hashes, and the result is stable across processes,
+// except for values containing pointers, channels or interfaces holding them.
+// If K is an interface type, the dynamic value is hashed, so the result does not depend on K.
+// 相等的值哈希值相同. 除了包含指针的值, 哈希值在不同进程中是稳定的
+func Of[K comparable](k K) uint64 {
+	switch v := any(k).(type) {
+	case string:
+		return String(v)
+	case int:
+		return Uint64(uint64(v))
+	case int8:
+		return Uint64(uint64(v))
+	case int16:
+		return Uint64(uint64(v))
+	case int32:
+		return Uint64(uint64(v))
+	case int64:
+		return Uint64(uint64(v))
+	case uint:
+		return Uint64(uint64(v))
+	case uint8:
+		return Uint64(uint64(v))
+	case uint16:
+		return Uint64(uint64(v))
+	case uint32:
+		return Uint64(uint64(v))
+	case uint64:
+		return Uint64(v)
+	case uintptr:
+		return Uint64(uint64(v))
+	case float32:
+		return Float64(float64(v))
+	case float64:
+		return Float64(v)
+	case bool:
+		if v {
+			return Uint64(1)
+		}
+		return Uint64(0)
+	}
+	// 其他可比较类型(命名类型、结构体、数组、指针等): 按 == 的规则逐个字段/元素哈希.
+	// K 是接口类型时哈希其动态值, 所以结果与 K 是动态值的类型时相同
+	return Value(reflect.ValueOf(any(k)))
+}
+
+// Value hashes a comparable reflect.Value following the rules of ==:
+// struct fields and array elements are hashed one by one, floats by Float64 (so -0.0 equals 0.0),
+// pointers and channels by address, interfaces by their dynamic type and value.
+// 按 == 的规则哈希可比较的值: 逐个哈希结构体字段和数组元素, 浮点数使用 Float64(所以 -0.0 与 0.0 相同),
+// 指针和通道按地址, 接口按其动态类型和值
+func Value(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Invalid: // nil 接口
+		return Uint64(0)
+	case reflect.Bool:
+		if v.Bool() {
+			return Uint64(1)
+		}
+		return Uint64(0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return Uint64(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return Uint64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return Float64(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return combine(Float64(real(c)), Float64(imag(c)))
+	case reflect.String:
+		return String(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		return Uint64(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			return Uint64(0)
+		}
+		e := v.Elem()
+		// 动态类型不同的值不相等, 类型名相同的不同类型只会导致哈希冲突
+		return combine(String(e.Type().String()), Value(e))
+	case reflect.Array:
+		h := Uint64(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			h = combine(h, Value(v.Index(i)))
+		}
+		return h
+	case reflect.Struct:
+		h := Uint64(uint64(v.NumField()))
+		for i := 0; i < v.NumField(); i++ {
+			h = combine(h, Value(v.Field(i)))
+		}
+		return h
+	}
+	panic("hashing: " + v.Type().String() + " is not comparable")
+}
+
+// combine 组合两个哈希值, 与顺序有关
+func combine(h, x uint64) uint64 {
+	return Uint64(h + x)
+}
+
+// String hashes a string with FNV-1a then mixes the bits.
+func String(s string) uint64 {
+	const offset64, prime64 = 14695981039346656037, 1099511628211
+	h := uint64(offset64)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime64
+	}
+	return Uint64(h)
+}
+
+// Float64 hashes a float, 0.0 and -0.0 are equal so they have the same hash.
+func Float64(f float64) uint64 {
+	if f == 0 {
+		f = 0
+	}
+	return Uint64(math.Float64bits(f))
+}
+
+// Uint64 mixes the bits of x (the splitmix64 finalizer), so that close numbers have unrelated hashes.
+func Uint64(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
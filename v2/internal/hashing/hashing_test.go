@@ -0,0 +1,39 @@
+package hashing
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOfFollowsEqual(t *testing.T) {
+	type point struct {
+		X, Y float64
+		Tag  any
+	}
+	type id int
+	negZero := math.Copysign(0, -1)
+	pairs := [][2]any{
+		{point{X: 0, Tag: "a"}, point{X: negZero, Tag: "a"}},
+		{[2]float64{0, 1}, [2]float64{negZero, 1}},
+		{id(3), id(3)},
+		{any(point{Y: negZero}), any(point{})},
+	}
+	for _, p := range pairs {
+		if p[0] != p[1] {
+			t.Fatalf("%v != %v", p[0], p[1])
+		}
+		if Of(p[0]) != Of(p[1]) {
+			t.Errorf("Of(%#v) != Of(%#v)", p[0], p[1])
+		}
+	}
+	if Of(point{X: 1}) == Of(point{Y: 1}) || Of([2]int{1, 2}) == Of([2]int{2, 1}) {
+		t.Errorf("fields or elements in different positions should have different hashes")
+	}
+	if Of(id(3)) != Of(3) {
+		t.Errorf("named integers should hash like their underlying type")
+	}
+	if Of[any](point{X: 1}) != Of(point{X: 1}) {
+		t.Errorf("interface keys should hash like their dynamic values")
+	}
+	Of[any](nil) // a nil interface does not panic
+}
@@ -47,6 +47,45 @@ func Maps[T, R any](it Seq[T], f types.Function[T, R]) Seq[R] {
 	return Seq[R](Map(iter.Seq[T](it), f))
 }
 
+// MapRetry like Map, but retries fn by the policy when it returns an error.
+// When an element still fails after the retries (or the error is not retryable),
+// it is passed to policy.DeadLetter and skipped if DeadLetter is set, otherwise MapRetry panics with a *RetryError.
+// 同 Map, fn 返回错误时按 policy 重试. 重试耗尽(或错误不可重试)时,
+// 如果设置了 policy.DeadLetter, 将元素和错误交给它并跳过该元素, 否则 panic(*RetryError)
+func MapRetry[T, R any](it iter.Seq[T], fn func(T) (R, error), policy RetryPolicy[T]) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		for e := range it {
+			r, attempts, err := retry(&policy, e, fn)
+			switch {
+			case err == nil:
+				if !yield(r) {
+					return
+				}
+			case policy.DeadLetter != nil:
+				policy.DeadLetter(e, err)
+			default:
+				panic(&RetryError{Element: e, Attempts: attempts, Err: err})
+			}
+		}
+	}
+}
+
+// MapOrElse like Map, but uses fallback(element, err) as the result when fn returns an error.
+// 同 Map, fn 返回错误时使用 fallback 的结果
+func MapOrElse[T, R any](it iter.Seq[T], fn func(T) (R, error), fallback func(T, error) R) iter.Seq[R] {
+	return func(yield func(R) bool) {
+		for e := range it {
+			r, err := fn(e)
+			if err != nil {
+				r = fallback(e, err)
+			}
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
+
 // FlatMap transform each element in Seq[T] to a new Seq[R].
 // 将原本序列中的每个元素都转换为一个新的序列，
 // 并将所有转换后的序列依次连接起来生成一个新的序列
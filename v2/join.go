@@ -0,0 +1,263 @@
+package stream
+
+import (
+	"iter"
+
+	"github.com/youthlin/stream/v2/optional"
+	"github.com/youthlin/stream/v2/types"
+)
+
+// Join is a hash inner join, it yields a Pair for each (left, right) whose keys are equal.
+// The hash table is built on the smaller side: both sides are pulled alternately until one ends,
+// so only the smaller side and an equal-length prefix of the other are held in memory.
+// The left side is taken as the smaller one when both have the same length.
+// The output follows the order of the larger side, pairs of the same larger element follow the order of the smaller side.
+// 哈希内连接, 对每对 key 相等的 (left, right) 返回一个 Pair.
+// 哈希表建立在较小的一侧: 交替读取两侧直到一侧结束, 内存中只保存较小的一侧和另一侧等长的前缀. 两侧一样长时视左侧为较小的一侧.
+// 输出按较大一侧的顺序, 较大一侧同一个元素的多个结果按较小一侧的顺序
+func Join[L, R any, K comparable](left iter.Seq[L], right iter.Seq[R],
+	leftKey types.Function[L, K], rightKey types.Function[R, K]) iter.Seq[types.Pair[L, R]] {
+	return func(yield func(types.Pair[L, R]) bool) {
+		s := pullSides(left, right)
+		defer s.stop()
+		if s.leftSmaller() {
+			hashJoin(s.leftBuf, s.rightRest(), leftKey, rightKey, func(l L, r R) bool {
+				return yield(types.Pair[L, R]{First: l, Second: r})
+			}, nil, nil)
+		} else {
+			hashJoin(s.rightBuf, s.leftRest(), rightKey, leftKey, func(r R, l L) bool {
+				return yield(types.Pair[L, R]{First: l, Second: r})
+			}, nil, nil)
+		}
+	}
+}
+
+// LeftJoin is a hash left outer join, like Join, but a left element without match
+// is yielded once with an absent right side.
+// The order depends on which side is smaller, as Join: if the right side is smaller, all pairs follow the left order;
+// if the left side is smaller, the matched pairs follow the right order and the left elements without match are yielded last, in left order.
+// 哈希左外连接, 同 Join, 但没有匹配的左侧元素也会返回一次, 其右侧为空.
+// 输出顺序取决于哪一侧较小(同 Join): 右侧较小时按左侧的顺序; 左侧较小时匹配的结果按右侧的顺序, 没有匹配的左侧元素在最后按左侧顺序返回
+func LeftJoin[L, R any, K comparable](left iter.Seq[L], right iter.Seq[R],
+	leftKey types.Function[L, K], rightKey types.Function[R, K]) iter.Seq[types.Pair[L, types.Optional[R]]] {
+	return func(yield func(types.Pair[L, types.Optional[R]]) bool) {
+		emit := func(l L, r types.Optional[R]) bool {
+			return yield(types.Pair[L, types.Optional[R]]{First: l, Second: r})
+		}
+		s := pullSides(left, right)
+		defer s.stop()
+		if s.leftSmaller() {
+			hashJoin(s.leftBuf, s.rightRest(), leftKey, rightKey, func(l L, r R) bool {
+				return emit(l, optional.Of(r))
+			}, nil, func(l L) bool {
+				return emit(l, optional.Nil[R]())
+			})
+		} else {
+			hashJoin(s.rightBuf, s.leftRest(), rightKey, leftKey, func(r R, l L) bool {
+				return emit(l, optional.Of(r))
+			}, func(l L) bool {
+				return emit(l, optional.Nil[R]())
+			}, nil)
+		}
+	}
+}
+
+// FullOuterJoin is a hash full outer join, like Join, but an element of either side without match
+// is yielded once with the other side absent.
+// The order depends on which side is smaller, as Join: the pairs follow the order of the larger side,
+// including its elements without match, then the elements of the smaller side without match are yielded last, in their order.
+// 哈希全外连接, 同 Join, 但任一侧没有匹配的元素也会返回一次, 其另一侧为空.
+// 输出顺序取决于哪一侧较小(同 Join): 按较大一侧的顺序(包括其没有匹配的元素), 较小一侧没有匹配的元素在最后按其顺序返回
+func FullOuterJoin[L, R any, K comparable](left iter.Seq[L], right iter.Seq[R],
+	leftKey types.Function[L, K], rightKey types.Function[R, K]) iter.Seq[types.Pair[types.Optional[L], types.Optional[R]]] {
+	return func(yield func(types.Pair[types.Optional[L], types.Optional[R]]) bool) {
+		emit := func(l types.Optional[L], r types.Optional[R]) bool {
+			return yield(types.Pair[types.Optional[L], types.Optional[R]]{First: l, Second: r})
+		}
+		onlyLeft := func(l L) bool { return emit(optional.Of(l), optional.Nil[R]()) }
+		onlyRight := func(r R) bool { return emit(optional.Nil[L](), optional.Of(r)) }
+		s := pullSides(left, right)
+		defer s.stop()
+		if s.leftSmaller() {
+			hashJoin(s.leftBuf, s.rightRest(), leftKey, rightKey, func(l L, r R) bool {
+				return emit(optional.Of(l), optional.Of(r))
+			}, onlyRight, onlyLeft)
+		} else {
+			hashJoin(s.rightBuf, s.leftRest(), rightKey, leftKey, func(r R, l L) bool {
+				return emit(optional.Of(l), optional.Of(r))
+			}, onlyLeft, onlyRight)
+		}
+	}
+}
+
+// SemiJoin yields the left elements which have at least one right element with the same key, in left order.
+// Only the keys of the right side are held in memory.
+// 半连接: 按左侧顺序返回在右侧存在相同 key 的左侧元素. 内存中只保存右侧的 key
+func SemiJoin[L, R any, K comparable](left iter.Seq[L], right iter.Seq[R],
+	leftKey types.Function[L, K], rightKey types.Function[R, K]) iter.Seq[L] {
+	return filterByKeys(left, right, leftKey, rightKey, true)
+}
+
+// AntiJoin yields the left elements which have no right element with the same key, in left order.
+// Only the keys of the right side are held in memory.
+// 反连接: 按左侧顺序返回在右侧不存在相同 key 的左侧元素. 内存中只保存右侧的 key
+func AntiJoin[L, R any, K comparable](left iter.Seq[L], right iter.Seq[R],
+	leftKey types.Function[L, K], rightKey types.Function[R, K]) iter.Seq[L] {
+	return filterByKeys(left, right, leftKey, rightKey, false)
+}
+
+func filterByKeys[L, R any, K comparable](left iter.Seq[L], right iter.Seq[R],
+	leftKey types.Function[L, K], rightKey types.Function[R, K], keep bool) iter.Seq[L] {
+	return func(yield func(L) bool) {
+		keys := make(map[K]struct{})
+		for r := range right {
+			keys[rightKey(r)] = struct{}{}
+		}
+		for l := range left {
+			if _, ok := keys[leftKey(l)]; ok == keep && !yield(l) {
+				return
+			}
+		}
+	}
+}
+
+// SortedMergeJoin is an inner join of two Seqs which are both sorted by key in ascending order of cmp.
+// Only the right elements sharing the current key are held in memory.
+// The output is in key order, pairs of the same key follow the left order then the right order.
+// 排序合并内连接, 两侧都需要按 key 以 cmp 升序排列. 内存中只保存右侧与当前 key 相同的元素.
+// 输出按 key 排序, 相同 key 的结果按左侧顺序、再按右侧顺序
+func SortedMergeJoin[L, R, K any](left iter.Seq[L], right iter.Seq[R],
+	leftKey types.Function[L, K], rightKey types.Function[R, K], cmp types.Comparator[K]) iter.Seq[types.Pair[L, R]] {
+	return func(yield func(types.Pair[L, R]) bool) {
+		nextL, stopL := iter.Pull(left)
+		defer stopL()
+		nextR, stopR := iter.Pull(right)
+		defer stopR()
+		l, okL := nextL()
+		r, okR := nextR()
+		var group []R
+		for okL && okR {
+			k := rightKey(r)
+			c := cmp(leftKey(l), k)
+			if c < 0 {
+				l, okL = nextL()
+				continue
+			}
+			if c > 0 {
+				r, okR = nextR()
+				continue
+			}
+			group = append(group[:0], r)
+			for r, okR = nextR(); okR && cmp(rightKey(r), k) == 0; r, okR = nextR() {
+				group = append(group, r)
+			}
+			for ; okL && cmp(leftKey(l), k) == 0; l, okL = nextL() {
+				for _, g := range group {
+					if !yield(types.Pair[L, R]{First: l, Second: g}) {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// sides 交替读取两侧, 直到一侧结束
+type sides[L, R any] struct {
+	leftBuf  []L
+	rightBuf []R
+	leftDone bool // 左侧先结束
+	nextL    func() (L, bool)
+	nextR    func() (R, bool)
+	stopL    func()
+	stopR    func()
+}
+
+func pullSides[L, R any](left iter.Seq[L], right iter.Seq[R]) *sides[L, R] {
+	s := &sides[L, R]{}
+	s.nextL, s.stopL = iter.Pull(left)
+	s.nextR, s.stopR = iter.Pull(right)
+	for {
+		l, ok := s.nextL()
+		if !ok {
+			s.leftDone = true
+			return s
+		}
+		s.leftBuf = append(s.leftBuf, l)
+		r, ok := s.nextR()
+		if !ok {
+			return s
+		}
+		s.rightBuf = append(s.rightBuf, r)
+	}
+}
+
+// leftSmaller 左侧已全部读取, 在左侧建立哈希表
+func (s *sides[L, R]) leftSmaller() bool {
+	return s.leftDone
+}
+
+func (s *sides[L, R]) stop() {
+	s.stopL()
+	s.stopR()
+}
+
+// leftRest 左侧已读取的部分和剩余部分
+func (s *sides[L, R]) leftRest() iter.Seq[L] {
+	return rest(s.leftBuf, s.nextL)
+}
+
+// rightRest 右侧已读取的部分和剩余部分
+func (s *sides[L, R]) rightRest() iter.Seq[R] {
+	return rest(s.rightBuf, s.nextR)
+}
+
+func rest[T any](buf []T, next func() (T, bool)) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for _, e := range buf {
+			if !yield(e) {
+				return
+			}
+		}
+		for e, ok := next(); ok; e, ok = next() {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// hashJoin 在 build 上建立哈希表, 按顺序用 probe 中的元素查找.
+// 对每个匹配调用 match; probeMiss 不为 nil 时对没有匹配的 probe 元素调用;
+// buildMiss 不为 nil 时, 最后按 build 的顺序对没有匹配的 build 元素调用. 任一回调返回 false 时结束
+func hashJoin[B, P any, K comparable](build []B, probe iter.Seq[P], buildKey types.Function[B, K], probeKey types.Function[P, K],
+	match func(B, P) bool, probeMiss func(P) bool, buildMiss func(B) bool) {
+	table := make(map[K][]int, len(build))
+	for i, b := range build {
+		k := buildKey(b)
+		table[k] = append(table[k], i)
+	}
+	var matched []bool
+	if buildMiss != nil {
+		matched = make([]bool, len(build))
+	}
+	for p := range probe {
+		indexes := table[probeKey(p)]
+		if len(indexes) == 0 && probeMiss != nil && !probeMiss(p) {
+			return
+		}
+		for _, i := range indexes {
+			if matched != nil {
+				matched[i] = true
+			}
+			if !match(build[i], p) {
+				return
+			}
+		}
+	}
+	for i, ok := range matched {
+		if !ok && !buildMiss(build[i]) {
+			return
+		}
+	}
+}
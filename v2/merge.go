@@ -0,0 +1,184 @@
+package stream
+
+import (
+	"container/heap"
+	"iter"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// MergeSorted lazily merges Seqs which are each sorted in ascending order of cmp, using a heap.
+// Equal elements keep the order of their Seqs in the arguments, so the merge is stable.
+// Only the current element of each Seq is held in memory.
+// 使用堆惰性合并多个已按 cmp 升序排列的序列. 相等的元素按其所在序列在参数中的顺序返回, 所以合并是稳定的.
+// 内存中只保存每个序列的当前元素
+func MergeSorted[T any](cmp types.Comparator[T], seqs ...iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		h := &mergeHeap[T]{cmp: cmp}
+		defer func() {
+			for _, c := range h.cursors {
+				c.stop()
+			}
+		}()
+		for i, seq := range seqs {
+			c := &cursor[T]{index: i}
+			c.next, c.stop = iter.Pull(seq)
+			if c.advance() {
+				h.cursors = append(h.cursors, c)
+			} else {
+				c.stop()
+			}
+		}
+		heap.Init(h)
+		for h.Len() > 0 {
+			c := h.cursors[0]
+			if !yield(c.val) {
+				return
+			}
+			if c.advance() {
+				heap.Fix(h, 0)
+			} else {
+				c.stop()
+				heap.Pop(h)
+			}
+		}
+	}
+}
+
+// UnionSorted yields each distinct element of the sorted Seqs once, in ascending order.
+// Of equal elements, the one from the first Seq in the arguments is yielded.
+// 有序并集: 按升序返回各有序序列中的每个不同元素一次, 相等的元素返回参数中靠前序列的那个
+func UnionSorted[T any](cmp types.Comparator[T], seqs ...iter.Seq[T]) iter.Seq[T] {
+	return dedupSorted(MergeSorted(cmp, seqs...), cmp)
+}
+
+// IntersectSorted yields each element present in all the sorted Seqs once, in ascending order,
+// the element from the first Seq is yielded.
+// 有序交集: 按升序返回在所有有序序列中都出现的元素一次, 返回第一个序列中的元素
+func IntersectSorted[T any](cmp types.Comparator[T], seqs ...iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		if len(seqs) == 0 {
+			return
+		}
+		cursors := make([]*cursor[T], len(seqs))
+		for i, seq := range seqs {
+			c := &cursor[T]{index: i}
+			c.next, c.stop = iter.Pull(seq)
+			defer c.stop()
+			cursors[i] = c
+		}
+		for _, c := range cursors {
+			if !c.advance() {
+				return
+			}
+		}
+		for {
+			// 以各序列当前元素的最大值为目标, 其余序列跳过比它小的元素
+			target := cursors[0].val
+			for _, c := range cursors[1:] {
+				if cmp(c.val, target) > 0 {
+					target = c.val
+				}
+			}
+			all := true
+			for _, c := range cursors {
+				for cmp(c.val, target) < 0 {
+					if !c.advance() {
+						return
+					}
+				}
+				all = all && cmp(c.val, target) == 0
+			}
+			if !all {
+				continue
+			}
+			if !yield(cursors[0].val) {
+				return
+			}
+			for _, c := range cursors { // 跳过重复的元素
+				for cmp(c.val, target) == 0 {
+					if !c.advance() {
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// ExceptSorted yields each distinct element of the sorted Seq which is not in any of the sorted others,
+// in ascending order.
+// 有序差集: 按升序返回 seq 中不在其他任一有序序列中的不同元素
+func ExceptSorted[T any](cmp types.Comparator[T], seq iter.Seq[T], others ...iter.Seq[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		next, stop := iter.Pull(MergeSorted(cmp, others...))
+		defer stop()
+		other, ok := next()
+		for e := range dedupSorted(seq, cmp) {
+			for ok && cmp(other, e) < 0 {
+				other, ok = next()
+			}
+			if ok && cmp(other, e) == 0 {
+				continue
+			}
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// dedupSorted 去掉有序序列中连续的相等元素
+func dedupSorted[T any](it iter.Seq[T], cmp types.Comparator[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var last T
+		first := true
+		for e := range it {
+			if !first && cmp(last, e) == 0 {
+				continue
+			}
+			first, last = false, e
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// cursor 序列的当前元素
+type cursor[T any] struct {
+	val   T
+	index int // 序列在参数中的下标
+	next  func() (T, bool)
+	stop  func()
+}
+
+func (c *cursor[T]) advance() (ok bool) {
+	c.val, ok = c.next()
+	return
+}
+
+// mergeHeap 按当前元素排序的最小堆, 相等时按序列下标
+type mergeHeap[T any] struct {
+	cursors []*cursor[T]
+	cmp     types.Comparator[T]
+}
+
+func (h *mergeHeap[T]) Len() int { return len(h.cursors) }
+
+func (h *mergeHeap[T]) Less(i, j int) bool {
+	if c := h.cmp(h.cursors[i].val, h.cursors[j].val); c != 0 {
+		return c < 0
+	}
+	return h.cursors[i].index < h.cursors[j].index
+}
+
+func (h *mergeHeap[T]) Swap(i, j int) { h.cursors[i], h.cursors[j] = h.cursors[j], h.cursors[i] }
+
+func (h *mergeHeap[T]) Push(x any) { h.cursors = append(h.cursors, x.(*cursor[T])) }
+
+func (h *mergeHeap[T]) Pop() any {
+	last := h.cursors[len(h.cursors)-1]
+	h.cursors = h.cursors[:len(h.cursors)-1]
+	return last
+}
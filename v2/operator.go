@@ -0,0 +1,114 @@
+package stream
+
+import (
+	"iter"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// Operator is an operation not bound to any source,
+// it transforms a Seq[T] to a Seq[R], so it can change the element type.
+// 不绑定数据源的操作, 将 Seq[T] 转为 Seq[R], 可以改变元素类型
+type Operator[T, R any] func(iter.Seq[T]) iter.Seq[R]
+
+// Apply applies the operation to the Seq.
+func (op Operator[T, R]) Apply(it iter.Seq[T]) iter.Seq[R] {
+	return op(it)
+}
+
+// Then compose two Operators, the first is applied first.
+// 组合两个操作, 先执行 first 再执行 second
+func Then[T, U, R any](first Operator[T, U], second Operator[U, R]) Operator[T, R] {
+	return func(it iter.Seq[T]) iter.Seq[R] {
+		return second(first(it))
+	}
+}
+
+// Chain compose some Operators which do not change the element type into one.
+// 将多个不改变元素类型的操作组合为一个
+func Chain[T any](ops ...Operator[T, T]) Operator[T, T] {
+	return func(it iter.Seq[T]) iter.Seq[T] {
+		return Pipe(it, ops...)
+	}
+}
+
+// Pipe applies the Operators to the Seq in order.
+// 依次将操作应用到序列上
+func Pipe[T any](it iter.Seq[T], ops ...Operator[T, T]) iter.Seq[T] {
+	for _, op := range ops {
+		it = op(it)
+	}
+	return it
+}
+
+// Pipe2 applies two Operators to the Seq in order, the element type may change at each step.
+// 依次应用两个操作, 每一步都可以改变元素类型
+func Pipe2[T, A, B any](it iter.Seq[T], op1 Operator[T, A], op2 Operator[A, B]) iter.Seq[B] {
+	return op2(op1(it))
+}
+
+// Pipe3 like Pipe2, but applies three Operators.
+func Pipe3[T, A, B, C any](it iter.Seq[T], op1 Operator[T, A], op2 Operator[A, B], op3 Operator[B, C]) iter.Seq[C] {
+	return op3(op2(op1(it)))
+}
+
+// Pipe4 like Pipe2, but applies four Operators.
+func Pipe4[T, A, B, C, D any](it iter.Seq[T], op1 Operator[T, A], op2 Operator[A, B], op3 Operator[B, C], op4 Operator[C, D]) iter.Seq[D] {
+	return op4(op3(op2(op1(it))))
+}
+
+// FilterOp is the Operator version of Filter.
+func FilterOp[T any](test types.Predicate[T]) Operator[T, T] {
+	return func(it iter.Seq[T]) iter.Seq[T] {
+		return Filter(it, test)
+	}
+}
+
+// MapOp is the Operator version of Map.
+func MapOp[T, R any](f types.Function[T, R]) Operator[T, R] {
+	return func(it iter.Seq[T]) iter.Seq[R] {
+		return Map(it, f)
+	}
+}
+
+// FlatMapOp is the Operator version of FlatMap.
+func FlatMapOp[T, R any](flatten types.Function[T, iter.Seq[R]]) Operator[T, R] {
+	return func(it iter.Seq[T]) iter.Seq[R] {
+		return FlatMap(it, flatten)
+	}
+}
+
+// PeekOp is the Operator version of Peek.
+func PeekOp[T any](accept types.Consumer[T]) Operator[T, T] {
+	return func(it iter.Seq[T]) iter.Seq[T] {
+		return Peek(it, accept)
+	}
+}
+
+// DistinctOp is the Operator version of Distinct.
+func DistinctOp[T any, Cmp comparable](f types.Function[T, Cmp]) Operator[T, T] {
+	return func(it iter.Seq[T]) iter.Seq[T] {
+		return Distinct(it, f)
+	}
+}
+
+// SortedOp is the Operator version of Sorted.
+func SortedOp[T any](cmp types.Comparator[T]) Operator[T, T] {
+	return func(it iter.Seq[T]) iter.Seq[T] {
+		return Sorted(it, cmp)
+	}
+}
+
+// LimitOp is the Operator version of Limit.
+func LimitOp[T any, Number types.Int](limit Number) Operator[T, T] {
+	return func(it iter.Seq[T]) iter.Seq[T] {
+		return Limit(it, limit)
+	}
+}
+
+// SkipOp is the Operator version of Skip.
+func SkipOp[T any, Number types.Int](skip Number) Operator[T, T] {
+	return func(it iter.Seq[T]) iter.Seq[T] {
+		return Skip(it, skip)
+	}
+}
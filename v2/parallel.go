@@ -0,0 +1,169 @@
+package stream
+
+import (
+	"iter"
+	"runtime"
+	"slices"
+	"sync"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// parallelismOf 并行度, 不大于 0 时使用 GOMAXPROCS
+func parallelismOf(parallelism int) int {
+	if parallelism <= 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return parallelism
+}
+
+// chunks 将 [0, size) 均匀切分为至多 n 个非空区间.
+// 切分方式只取决于 size 和 n, 与执行时机无关, 因此并行计算的结果是确定的
+func chunks(size, n int) [][2]int {
+	n = min(n, size)
+	result := make([][2]int, 0, n)
+	for i := range n {
+		result = append(result, [2]int{i * size / n, (i + 1) * size / n})
+	}
+	return result
+}
+
+// parallelDo 在 n 个 goroutine 上分别执行 f(0)...f(n-1), 等待全部完成.
+// 如果有 panic, 在调用方 goroutine 上重新抛出下标最小的那个
+func parallelDo(n int, f func(i int)) {
+	panics := make([]any, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := range n {
+		go func() {
+			defer wg.Done()
+			defer func() {
+				panics[i] = recover()
+			}()
+			f(i)
+		}()
+	}
+	wg.Wait()
+	for _, p := range panics {
+		if p != nil {
+			panic(p)
+		}
+	}
+}
+
+// combineTree 按固定的树形顺序两两合并结果: ((r0+r1)+(r2+r3))+...
+func combineTree[R any](results []R, combiner types.BinaryOperator[R]) R {
+	for len(results) > 1 {
+		next := make([]R, 0, (len(results)+1)/2)
+		for i := 0; i < len(results); i += 2 {
+			if i+1 < len(results) {
+				next = append(next, combiner(results[i], results[i+1]))
+			} else {
+				next = append(next, results[i])
+			}
+		}
+		results = next
+	}
+	return results[0]
+}
+
+// ParallelReduce split the slice to at most `parallelism` ranges,
+// reduce each range concurrently starting from identity,
+// then combine the results in a fixed tree order, so the result is deterministic for a given parallelism,
+// e.g. floating-point sums are reproducible run to run.
+// The split depends on parallelism, so a non-associative acc or combiner may give different results
+// for different parallelism. If parallelism <= 0, runtime.GOMAXPROCS(0) is used, which differs between machines;
+// pass a fixed parallelism to reproduce results across machines.
+// identity is shared by all ranges, so acc must not modify it.
+// To reduce a Seq in parallel, Collect it first.
+// 并行归约: 将切片切分为至多 parallelism 个区间, 每个区间从 identity 开始使用 acc 累计,
+// 再按固定的树形顺序使用 combiner 合并各区间的结果, 因此(例如浮点数求和)并行度相同时每次运行的结果相同.
+// 切分方式取决于并行度: acc 或 combiner 不满足结合律时, 不同的 parallelism 可能得到不同的结果.
+// parallelism 不大于 0 时使用 runtime.GOMAXPROCS(0), 其在不同机器上可能不同, 需要跨机器复现结果时应传入固定的 parallelism.
+// identity 会被各区间共享, acc 不能修改它
+func ParallelReduce[T, R any](elements []T, identity R, acc types.BiFunction[R, T, R],
+	combiner types.BinaryOperator[R], parallelism int) R {
+	ranges := chunks(len(elements), parallelismOf(parallelism))
+	if len(ranges) == 0 {
+		return identity
+	}
+	results := make([]R, len(ranges))
+	parallelDo(len(ranges), func(i int) {
+		result := identity
+		for _, e := range elements[ranges[i][0]:ranges[i][1]] {
+			result = acc(result, e)
+		}
+		results[i] = result
+	})
+	return combineTree(results, combiner)
+}
+
+// parallelSortThreshold 元素少于该值时并行排序没有收益, 按顺序排序
+const parallelSortThreshold = 1 << 13
+
+// ParallelSorted sorts the elements stably on at most `parallelism` goroutines:
+// chunks are sorted concurrently, then adjacent chunks are merged pairwise,
+// the merges of the same level run concurrently.
+// Small Seqs are sorted sequentially. cmp must be safe for concurrent use.
+// If parallelism <= 0, runtime.GOMAXPROCS(0) is used.
+// 并行稳定排序: 分块并行排序后逐层两两合并, 同一层的合并并行执行; 元素较少时按顺序排序. cmp 需要能被并发调用
+func ParallelSorted[T any](it iter.Seq[T], cmp types.Comparator[T], parallelism int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		vals := Collect(it)
+		parallelSort(vals, cmp, parallelismOf(parallelism))
+		for _, v := range vals {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// parallelSort 并行稳定排序切片
+func parallelSort[T any](list []T, cmp types.Comparator[T], n int) {
+	if len(list) < parallelSortThreshold || n < 2 {
+		slices.SortStableFunc(list, cmp)
+		return
+	}
+	runs := chunks(len(list), n)
+	parallelDo(len(runs), func(i int) {
+		slices.SortStableFunc(list[runs[i][0]:runs[i][1]], cmp)
+	})
+	src, dst := list, make([]T, len(list))
+	for len(runs) > 1 {
+		next := make([][2]int, (len(runs)+1)/2)
+		parallelDo(len(next), func(i int) {
+			left := runs[2*i]
+			if 2*i+1 == len(runs) { // 落单的块直接复制
+				copy(dst[left[0]:left[1]], src[left[0]:left[1]])
+				next[i] = left
+				return
+			}
+			right := runs[2*i+1]
+			merge(dst[left[0]:right[1]], src[left[0]:left[1]], src[right[0]:right[1]], cmp)
+			next[i] = [2]int{left[0], right[1]}
+		})
+		runs = next
+		src, dst = dst, src
+	}
+	if &src[0] != &list[0] {
+		copy(list, src)
+	}
+}
+
+// merge 将有序的 left, right 合并到 dst. 相等时先取 left 中的元素, 所以是稳定的
+func merge[T any](dst, left, right []T, cmp types.Comparator[T]) {
+	i, j, k := 0, 0, 0
+	for i < len(left) && j < len(right) {
+		if cmp(right[j], left[i]) < 0 {
+			dst[k] = right[j]
+			j++
+		} else {
+			dst[k] = left[i]
+			i++
+		}
+		k++
+	}
+	k += copy(dst[k:], left[i:])
+	copy(dst[k:], right[j:])
+}
@@ -0,0 +1,194 @@
+package stream
+
+import (
+	"context"
+	"iter"
+	"sync"
+
+	"github.com/youthlin/stream/v2/internal/hashing"
+	"github.com/youthlin/stream/v2/types"
+)
+
+// laneBuffer 每个分区通道的缓冲大小
+const laneBuffer = 64
+
+// laneOf 按 key 的哈希值选择分区
+func laneOf[K comparable](k K, lanes int) int {
+	return int(hashing.Of(k) % uint64(lanes))
+}
+
+// firstPanic 记录多个 goroutine 中第一个 panic 的值, 并取消其他 goroutine.
+// 数据源的 goroutine 可能在调用方返回后才 panic, 所以读写都需要加锁
+type firstPanic struct {
+	mu     sync.Mutex
+	val    any
+	ok     bool
+	cancel context.CancelFunc
+}
+
+// recover 必须直接 defer 调用
+func (p *firstPanic) recover() {
+	if r := recover(); r != nil {
+		p.mu.Lock()
+		if !p.ok {
+			p.val, p.ok = r, true
+		}
+		p.mu.Unlock()
+		p.cancel()
+	}
+}
+
+// rethrow 在调用方 goroutine 上重新抛出记录的 panic
+func (p *firstPanic) rethrow() {
+	p.mu.Lock()
+	val, ok := p.val, p.ok
+	p.mu.Unlock()
+	if ok {
+		panic(val)
+	}
+}
+
+// partition 启动 workers 个分区 goroutine, 每个 goroutine 依次处理一个分区中的元素.
+// dispatch 按 key 把元素发往对应分区, 在 ctx 取消时返回 false;
+// 数据源结束后由分发方调用 closeLanes 关闭所有分区; wait 等待所有分区 goroutine 退出:
+// 分区关闭后处理完剩余的元素才退出, ctx 取消时立即退出并丢弃剩余的元素, 不需要等待分发方
+func partition[T any, K comparable](ctx context.Context, workers int, key types.Function[T, K], handle func(lane int, e T), p *firstPanic) (dispatch func(T) bool, closeLanes func(), wait func()) {
+	lanes := make([]chan T, workers)
+	var wg sync.WaitGroup
+	for i := range lanes {
+		lanes[i] = make(chan T, laneBuffer)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer p.recover()
+			for {
+				select {
+				case e, ok := <-lanes[i]:
+					if !ok || ctx.Err() != nil {
+						return
+					}
+					handle(i, e)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	dispatch = func(e T) bool {
+		select {
+		case lanes[laneOf(key(e), workers)] <- e:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+	closeLanes = func() {
+		for _, lane := range lanes {
+			close(lane)
+		}
+	}
+	return dispatch, closeLanes, wg.Wait
+}
+
+// feed 在单独的 goroutine 中迭代数据源并分发元素, 结束后关闭所有分区.
+// 数据源阻塞时(例如等待通道或网络)调用方仍可以在 ctx 取消时立即返回,
+// 该 goroutine 会在数据源下一次产生元素(或结束)时退出
+func feed[T any](it iter.Seq[T], dispatch func(T) bool, closeLanes func(), p *firstPanic) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer closeLanes()
+		defer p.recover() // 数据源的 panic
+		for e := range it {
+			if !dispatch(e) {
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// PartitionBy processes elements on `workers` goroutines (lanes).
+// Elements with the same key are sent to the same lane, so they are handled in order,
+// while elements with different keys may be handled in parallel.
+// It blocks until all elements are handled and returns nil,
+// or returns ctx.Err() when ctx is cancelled, the elements not handled yet are dropped.
+// The source is iterated on its own goroutine, so it returns promptly even if the source is blocked;
+// that goroutine exits when the source yields again or ends.
+// A panic in handler cancels the others and is re-raised on the calling goroutine.
+// 按 key 将元素分配到 workers 个 goroutine 上处理, 相同 key 的元素按顺序处理, 不同 key 的元素可以并行处理.
+// 处理完所有元素后返回 nil; ctx 取消时丢弃未处理的元素, 返回 ctx.Err().
+// 数据源在单独的 goroutine 中迭代, 所以即使数据源阻塞也能及时返回, 该 goroutine 在数据源下一次产生元素或结束时退出.
+// handler 中的 panic 会在调用方 goroutine 上重新抛出
+func PartitionBy[T any, K comparable](ctx context.Context, it iter.Seq[T], key types.Function[T, K], workers int, handler func(lane int, e T)) error {
+	workers = max(workers, 1)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	p := &firstPanic{cancel: cancel}
+	dispatch, closeLanes, wait := partition(ctx, workers, key, handler, p)
+	select {
+	case <-feed(it, dispatch, closeLanes, p):
+	case <-ctx.Done():
+	}
+	wait()
+	p.rethrow()
+	return ctx.Err()
+}
+
+// MapWithState transform elements on `workers` goroutines, with a state per key.
+// The state of a key is created by init when the key first appears,
+// and f can read and update it. Elements with the same key are handled in order on the same goroutine,
+// so the state needs no lock, and results of the same key are yielded in input order.
+// Results of different keys may be interleaved.
+// The Seq ends when the source ends or ctx is cancelled.
+// When the consumer stops early, the lane goroutines are stopped before return.
+// The source is iterated on its own goroutine, so it does not block the return even if the source is blocked;
+// that goroutine exits when the source yields again or ends.
+// A panic in f is re-raised on the consuming goroutine.
+// 按 key 分区并行转换元素, 每个 key 有一个状态, 由 init 在 key 第一次出现时创建, f 可以读取和修改状态.
+// 相同 key 的元素在同一个 goroutine 上按顺序处理, 所以状态不需要加锁, 其结果也按输入顺序返回; 不同 key 的结果可能交错.
+// 数据源结束或 ctx 取消时序列结束; 消费者提前结束时会等待分区 goroutine 退出;
+// 数据源在单独的 goroutine 中迭代, 即使数据源阻塞也不会阻塞返回, 该 goroutine 在数据源下一次产生元素或结束时退出;
+// f 中的 panic 会在消费者的 goroutine 上重新抛出
+func MapWithState[T any, K comparable, S, R any](ctx context.Context, it iter.Seq[T], key types.Function[T, K], workers int,
+	init types.Function[K, S], f func(state *S, e T) R) iter.Seq[R] {
+	workers = max(workers, 1)
+	return func(yield func(R) bool) {
+		ctx, cancel := context.WithCancel(ctx)
+		p := &firstPanic{cancel: cancel}
+		out := make(chan R, workers)
+		states := make([]map[K]*S, workers) // 每个分区的状态, 只在分区 goroutine 中访问
+		for i := range states {
+			states[i] = make(map[K]*S)
+		}
+		dispatch, closeLanes, wait := partition(ctx, workers, key, func(lane int, e T) {
+			k := key(e)
+			state, ok := states[lane][k]
+			if !ok {
+				s := init(k)
+				state = &s
+				states[lane][k] = state
+			}
+			r := f(state, e)
+			select {
+			case out <- r:
+			case <-ctx.Done():
+			}
+		}, p)
+		feed(it, dispatch, closeLanes, p)
+		go func() { // 所有分区 goroutine 退出后结束
+			wait()
+			close(out)
+		}()
+		defer func() {
+			cancel() // 分区 goroutine 发送结果时会检查 ctx, 所以不需要排空 out
+			wait()
+			p.rethrow()
+		}()
+		for r := range out {
+			if !yield(r) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package stream
+
+import (
+	"iter"
+	"sync"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// Prefetch runs the upstream Seq in its own goroutine, which fills a channel of n elements ahead of the consumer,
+// so a slow producer and a slow consumer can work at the same time.
+// When the consumer stops early (e.g. Limit, FindFirst), the goroutine is stopped and waited before return.
+// A panic in the upstream is re-raised on the consuming goroutine.
+// For errors, use Prefetch2 with an iter.Seq2[T, error].
+// 在单独的 goroutine 中运行上游序列, 预先取出最多 n 个元素放入通道, 使慢的生产者和慢的消费者可以同时工作.
+// 消费者提前结束时会停止并等待该 goroutine; 上游的 panic 会在消费者的 goroutine 上重新抛出.
+// 需要传递错误时使用 Prefetch2
+func Prefetch[T any](it iter.Seq[T], n int) iter.Seq[T] {
+	return prefetch(it, n)
+}
+
+// Prefetch2 like Prefetch, but for Seq2, e.g. iter.Seq2[T, error].
+// 同 Prefetch, 用于 Seq2, 例如 iter.Seq2[T, error]
+func Prefetch2[K, V any](it iter.Seq2[K, V], n int) iter.Seq2[K, V] {
+	pairs := prefetch(func(yield func(types.Pair[K, V]) bool) {
+		for k, v := range it {
+			if !yield(types.Pair[K, V]{First: k, Second: v}) {
+				return
+			}
+		}
+	}, n)
+	return func(yield func(K, V) bool) {
+		for p := range pairs {
+			if !yield(p.First, p.Second) {
+				return
+			}
+		}
+	}
+}
+
+func prefetch[T any](it iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		ch := make(chan T, max(n, 0))
+		done := make(chan struct{})
+		var (
+			wg       sync.WaitGroup
+			panicked bool
+			panicVal any
+		)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(ch)
+			defer func() {
+				if r := recover(); r != nil {
+					panicked, panicVal = true, r
+				}
+			}()
+			for e := range it {
+				select {
+				case ch <- e:
+				case <-done:
+					return
+				}
+			}
+		}()
+		defer func() {
+			close(done)
+			wg.Wait()
+		}()
+		for e := range ch {
+			if !yield(e) {
+				return
+			}
+		}
+		if panicked { // ch 关闭后读取, 不存在竞争
+			panic(panicVal)
+		}
+	}
+}
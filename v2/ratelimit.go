@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"github.com/youthlin/stream/v2/clock"
+	"github.com/youthlin/stream/v2/types"
+)
+
+// bucket 令牌桶, 容量为 burst, 每秒补充 rate 个令牌
+type bucket struct {
+	tokens float64
+	last   time.Time // 上次补充的时间
+}
+
+// refill 补充令牌到 now, 返回桶是否已满
+func (b *bucket) refill(now time.Time, rate float64, burst int) bool {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(burst), b.tokens+elapsed.Seconds()*rate)
+		b.last = now
+	}
+	return b.tokens >= float64(burst)
+}
+
+// take 取一个令牌, 没有令牌时等待. ctx 取消时返回 false
+func (b *bucket) take(ctx context.Context, rate float64, burst int, clk clock.Clock) bool {
+	for {
+		b.refill(clk.Now(), rate, burst)
+		if b.tokens >= 1 {
+			b.tokens--
+			return true
+		}
+		wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+		timer := clk.NewTimer(max(wait, 1))
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		}
+	}
+}
+
+// checkRate 速率必须是正数, !(rate > 0) 包括 NaN; 否则等待时间无意义, 取令牌时会空转
+func checkRate(rate float64, burst int) {
+	if !(rate > 0) || burst < 1 {
+		panic("stream: RateLimit ratePerSecond must be positive and burst must be at least 1")
+	}
+}
+
+// RateLimit paces the elements by a token bucket: at most burst elements pass at once,
+// then about ratePerSecond elements pass per second. The bucket starts full.
+// The Seq ends when ctx is cancelled, including while waiting for a token.
+// The time is read from clk, use clock.System() for the real time.
+// It panics if ratePerSecond is not positive or burst is less than 1.
+// 令牌桶限流: 最多一次通过 burst 个元素, 之后每秒约通过 ratePerSecond 个元素, 初始时桶是满的.
+// ctx 取消时(包括等待令牌时)序列结束. 时间来自 clk, 真实时间使用 clock.System().
+// ratePerSecond 不是正数(包括 NaN)或 burst 小于 1 时 panic
+func RateLimit[T any](ctx context.Context, it iter.Seq[T], ratePerSecond float64, burst int, clk clock.Clock) iter.Seq[T] {
+	checkRate(ratePerSecond, burst)
+	return func(yield func(T) bool) {
+		b := &bucket{tokens: float64(burst), last: clk.Now()}
+		for e := range it {
+			if !b.take(ctx, ratePerSecond, burst, clk) || !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// RateLimitBy like RateLimit, but each key has its own token bucket.
+// An element waiting for its key blocks the elements after it, whatever their key is.
+// Buckets which have refilled to full are released, so idle keys do not hold memory.
+// It panics on the same arguments as RateLimit.
+// 同 RateLimit, 但每个 key 有单独的令牌桶. 等待令牌的元素会阻塞其后的所有元素. 已补满的令牌桶会被释放.
+// 参数不合法时 panic, 同 RateLimit
+func RateLimitBy[T any, K comparable](ctx context.Context, it iter.Seq[T], key types.Function[T, K],
+	ratePerSecond float64, burst int, clk clock.Clock) iter.Seq[T] {
+	checkRate(ratePerSecond, burst)
+	return func(yield func(T) bool) {
+		buckets := make(map[K]*bucket)
+		created := 0 // 上次清理后新建的令牌桶个数, 超过现有个数的一半时清理, 均摊开销为常数
+		for e := range it {
+			k := key(e)
+			b, ok := buckets[k]
+			if !ok {
+				if created++; created > len(buckets)/2 {
+					now := clk.Now()
+					for k, b := range buckets {
+						if b.refill(now, ratePerSecond, burst) {
+							delete(buckets, k)
+						}
+					}
+					created = 0
+				}
+				b = &bucket{tokens: float64(burst), last: clk.Now()}
+				buckets[k] = b
+			}
+			if !b.take(ctx, ratePerSecond, burst, clk) || !yield(e) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package reactive_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/youthlin/stream/v2/reactive"
+)
+
+// batchSubscriber requests `batch` elements at a time, and records the signals.
+type batchSubscriber struct {
+	batch    int64
+	sub      reactive.Subscription
+	pending  int64
+	received []string
+}
+
+func (b *batchSubscriber) OnSubscribe(s reactive.Subscription) {
+	b.sub = s
+	b.request()
+}
+
+func (b *batchSubscriber) request() {
+	b.received = append(b.received, fmt.Sprintf("request(%d)", b.batch))
+	b.pending = b.batch
+	b.sub.Request(b.batch)
+}
+
+func (b *batchSubscriber) OnNext(v int) {
+	b.received = append(b.received, fmt.Sprint(v))
+	b.pending--
+	if b.pending == 0 {
+		b.request() // requesting inside OnNext does not recurse
+	}
+}
+
+func (b *batchSubscriber) OnError(err error) {
+	b.received = append(b.received, "error: "+err.Error())
+}
+
+func (b *batchSubscriber) OnComplete() {
+	b.received = append(b.received, "complete")
+}
+
+func Example() {
+	numbers := reactive.FromSeq(slices.Values([]int{1, 2, 2, 3, 4, 5, 6, 7, 8, 9}))
+	p := reactive.Limit(reactive.Map(reactive.Filter(reactive.Distinct(numbers, func(i int) int {
+		return i
+	}), func(i int) bool {
+		return i%2 == 1
+	}), func(i int) int {
+		return i * 10
+	}), 4)
+	s := &batchSubscriber{batch: 2}
+	p.Subscribe(s)
+	fmt.Println(strings.Join(s.received, " "))
+	// Output:
+	// request(2) 10 30 request(2) 50 70 request(2) complete
+}
+
+func ExampleToSeq() {
+	p := reactive.Skip(reactive.FromSeq(slices.Values([]string{"a", "b", "c", "d"})), 1)
+	for s := range reactive.ToSeq(p) {
+		fmt.Println(s)
+		if s == "c" {
+			break // cancels the subscription
+		}
+	}
+	// Output:
+	// b
+	// c
+}
+
+func ExampleFromChan() {
+	events := make(chan string)
+	go func() {
+		defer close(events)
+		for _, e := range []string{"login", "click", "logout"} {
+			events <- e // blocks until a subscriber has demand
+		}
+	}()
+	ch, errc := reactive.ToChan(context.Background(), reactive.FromChan(events), 1)
+	for e := range ch {
+		fmt.Println(e)
+	}
+	fmt.Println(<-errc)
+	// Output:
+	// login
+	// click
+	// logout
+	// <nil>
+}
+
+func ExampleToSeq2() {
+	bad := reactive.PublisherFunc[int](func(s reactive.Subscriber[int]) {
+		s.OnSubscribe(noop{})
+		s.OnError(errors.New("broken"))
+	})
+	for v, err := range reactive.ToSeq2(bad) {
+		fmt.Println(v, err)
+	}
+	// Output:
+	// 0 broken
+}
+
+type noop struct{}
+
+func (noop) Request(int64) {}
+func (noop) Cancel()       {}
+
+type recorder struct {
+	sub  reactive.Subscription
+	next []int
+	err  error
+	done bool
+}
+
+func (r *recorder) OnSubscribe(s reactive.Subscription) { r.sub = s }
+func (r *recorder) OnNext(v int)                        { r.next = append(r.next, v) }
+func (r *recorder) OnError(err error)                   { r.err = err }
+func (r *recorder) OnComplete()                         { r.done = true }
+
+func TestFromSeqDemand(t *testing.T) {
+	r := &recorder{}
+	reactive.FromSeq(slices.Values([]int{1, 2, 3})).Subscribe(r)
+	if len(r.next) != 0 {
+		t.Fatalf("got %v before any request", r.next)
+	}
+	r.sub.Request(2)
+	if !slices.Equal(r.next, []int{1, 2}) || r.done {
+		t.Fatalf("after Request(2): next=%v done=%v", r.next, r.done)
+	}
+	r.sub.Request(reactive.Unbounded)
+	r.sub.Request(reactive.Unbounded) // no overflow
+	if !slices.Equal(r.next, []int{1, 2, 3}) || !r.done {
+		t.Fatalf("after Request(Unbounded): next=%v done=%v", r.next, r.done)
+	}
+
+	r = &recorder{}
+	reactive.FromSeq(slices.Values([]int{1, 2, 3})).Subscribe(r)
+	r.sub.Request(0)
+	if !errors.Is(r.err, reactive.ErrInvalidRequest) {
+		t.Fatalf("Request(0): err=%v", r.err)
+	}
+
+	r = &recorder{}
+	reactive.FromSeq(slices.Values([]int{1, 2, 3})).Subscribe(r)
+	r.sub.Request(1)
+	r.sub.Cancel()
+	r.sub.Request(1)
+	if !slices.Equal(r.next, []int{1}) || r.done {
+		t.Fatalf("after Cancel: next=%v done=%v", r.next, r.done)
+	}
+}
+
+func TestInvalidRequestConcurrent(t *testing.T) {
+	// concurrent invalid requests signal the error once, without a data race (go test -race)
+	for i := 0; i < 100; i++ {
+		errs := make(chan error, 2)
+		r := &errRecorder{errs: errs}
+		reactive.FromSeq(slices.Values([]int{1, 2, 3})).Subscribe(r)
+		var wg sync.WaitGroup
+		for j := 0; j < 2; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r.sub.Request(0)
+			}()
+		}
+		wg.Wait()
+		if len(errs) != 1 || !errors.Is(<-errs, reactive.ErrInvalidRequest) {
+			t.Fatalf("want exactly one ErrInvalidRequest")
+		}
+	}
+}
+
+// errRecorder sends the error signaled by OnError to errs.
+type errRecorder struct {
+	recorder
+	errs chan error
+}
+
+func (r *errRecorder) OnError(err error) { r.errs <- err }
+
+func TestLimitRequestsAtMostN(t *testing.T) {
+	var requested int64
+	source := reactive.PublisherFunc[int](func(s reactive.Subscriber[int]) {
+		reactive.FromSeq(slices.Values([]int{1, 2, 3, 4, 5})).Subscribe(&countingSubscriber{Subscriber: s, requested: &requested})
+	})
+	r := &recorder{}
+	reactive.Limit(source, 3).Subscribe(r)
+	r.sub.Request(reactive.Unbounded)
+	if !slices.Equal(r.next, []int{1, 2, 3}) || !r.done || requested != 3 {
+		t.Fatalf("next=%v done=%v requested=%d", r.next, r.done, requested)
+	}
+}
+
+func TestToSeqCancelsLateSubscription(t *testing.T) {
+	// 订阅在 ctx 结束后才到达时会被取消, 发布者的 goroutine 随之退出
+	cancelled := make(chan struct{})
+	late := reactive.PublisherFunc[int](func(s reactive.Subscriber[int]) {
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			s.OnSubscribe(cancelFunc(func() { close(cancelled) }))
+		}()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ch, _ := reactive.ToChan(ctx, late, 0)
+	for range ch {
+		t.Fatal("unexpected element")
+	}
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("late subscription is not cancelled")
+	}
+}
+
+// cancelFunc is a Subscription which ignores requests and calls itself on Cancel.
+type cancelFunc func()
+
+func (f cancelFunc) Request(int64) {}
+func (f cancelFunc) Cancel()       { f() }
+
+// countingSubscriber counts the demand requested from upstream.
+type countingSubscriber struct {
+	reactive.Subscriber[int]
+	requested *int64
+}
+
+func (c *countingSubscriber) OnSubscribe(s reactive.Subscription) {
+	c.Subscriber.OnSubscribe(&countingSubscription{Subscription: s, requested: c.requested})
+}
+
+type countingSubscription struct {
+	reactive.Subscription
+	requested *int64
+}
+
+func (c *countingSubscription) Request(n int64) {
+	*c.requested += n
+	c.Subscription.Request(n)
+}
@@ -0,0 +1,164 @@
+package reactive
+
+import (
+	"sync"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// Filter publishes the elements which pass the test.
+// A dropped element is replaced by requesting one more from upstream, so the demand is kept.
+// 过滤元素. 丢弃一个元素时会向上游多请求一个, 保持需求不变
+func Filter[T any](p Publisher[T], test types.Predicate[T]) Publisher[T] {
+	return PublisherFunc[T](func(s Subscriber[T]) {
+		p.Subscribe(&filterSubscriber[T]{down: s, test: test})
+	})
+}
+
+// Map transforms each element.
+// 转换每个元素
+func Map[T, R any](p Publisher[T], f types.Function[T, R]) Publisher[R] {
+	return PublisherFunc[R](func(s Subscriber[R]) {
+		p.Subscribe(&mapSubscriber[T, R]{down: s, f: f})
+	})
+}
+
+// Skip drops the first n elements.
+// 跳过前 n 个元素
+func Skip[T any, Number types.Int](p Publisher[T], n Number) Publisher[T] {
+	return PublisherFunc[T](func(s Subscriber[T]) {
+		skip := n // 每个订阅者单独计数
+		p.Subscribe(&filterSubscriber[T]{down: s, test: func(T) bool {
+			if skip > 0 {
+				skip--
+				return false
+			}
+			return true
+		}})
+	})
+}
+
+// Distinct drops the elements whose key has been seen, the key is computed by f.
+// 去重, 丢弃 key 已出现过的元素
+func Distinct[T any, Cmp comparable](p Publisher[T], f types.Function[T, Cmp]) Publisher[T] {
+	return PublisherFunc[T](func(s Subscriber[T]) {
+		seen := make(map[Cmp]struct{}) // 每个订阅者单独记录
+		p.Subscribe(&filterSubscriber[T]{down: s, test: func(e T) bool {
+			k := f(e)
+			if _, ok := seen[k]; ok {
+				return false
+			}
+			seen[k] = struct{}{}
+			return true
+		}})
+	})
+}
+
+// Limit publishes at most n elements, then cancels upstream and completes.
+// It never requests more than n elements from upstream.
+// 最多发布 n 个元素, 之后取消上游并完成. 向上游请求的总数不超过 n
+func Limit[T any, Number types.Int](p Publisher[T], n Number) Publisher[T] {
+	return PublisherFunc[T](func(s Subscriber[T]) {
+		p.Subscribe(&limitSubscriber[T]{down: s, remaining: int64(n), requestable: int64(n)})
+	})
+}
+
+type mapSubscriber[T, R any] struct {
+	down Subscriber[R]
+	f    types.Function[T, R]
+}
+
+func (m *mapSubscriber[T, R]) OnSubscribe(s Subscription) { m.down.OnSubscribe(s) }
+func (m *mapSubscriber[T, R]) OnNext(v T)                 { m.down.OnNext(m.f(v)) }
+func (m *mapSubscriber[T, R]) OnError(err error)          { m.down.OnError(err) }
+func (m *mapSubscriber[T, R]) OnComplete()                { m.down.OnComplete() }
+
+type filterSubscriber[T any] struct {
+	down Subscriber[T]
+	test types.Predicate[T]
+	up   Subscription
+}
+
+func (f *filterSubscriber[T]) OnSubscribe(s Subscription) {
+	f.up = s
+	f.down.OnSubscribe(s)
+}
+
+func (f *filterSubscriber[T]) OnNext(v T) {
+	if f.test(v) {
+		f.down.OnNext(v)
+	} else {
+		f.up.Request(1)
+	}
+}
+
+func (f *filterSubscriber[T]) OnError(err error) { f.down.OnError(err) }
+func (f *filterSubscriber[T]) OnComplete()       { f.down.OnComplete() }
+
+// limitSubscriber 同时作为下游的 Subscription, 限制向上游请求的个数
+type limitSubscriber[T any] struct {
+	down      Subscriber[T]
+	up        Subscription
+	remaining int64 // 还可以发布的个数, 只在信号中访问
+	done      bool
+
+	mu          sync.Mutex
+	requestable int64 // 还可以向上游请求的个数
+}
+
+func (l *limitSubscriber[T]) OnSubscribe(s Subscription) {
+	l.up = s
+	if l.remaining <= 0 {
+		s.Cancel()
+		l.done = true
+		l.down.OnSubscribe(l)
+		l.down.OnComplete()
+		return
+	}
+	l.down.OnSubscribe(l)
+}
+
+func (l *limitSubscriber[T]) OnNext(v T) {
+	if l.done {
+		return
+	}
+	l.remaining--
+	l.down.OnNext(v)
+	if l.remaining == 0 && !l.done {
+		l.done = true
+		l.up.Cancel()
+		l.down.OnComplete()
+	}
+}
+
+func (l *limitSubscriber[T]) OnError(err error) {
+	if !l.done {
+		l.done = true
+		l.down.OnError(err)
+	}
+}
+
+func (l *limitSubscriber[T]) OnComplete() {
+	if !l.done {
+		l.done = true
+		l.down.OnComplete()
+	}
+}
+
+func (l *limitSubscriber[T]) Request(n int64) {
+	if n <= 0 {
+		l.up.Request(n) // 由上游通知 ErrInvalidRequest
+		return
+	}
+	l.mu.Lock()
+	n = min(n, l.requestable)
+	l.requestable -= n
+	l.mu.Unlock()
+	if n > 0 {
+		l.up.Request(n)
+	}
+}
+
+func (l *limitSubscriber[T]) Cancel() {
+	l.up.Cancel()
+}
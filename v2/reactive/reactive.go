@@ -0,0 +1,67 @@
+// Package reactive is a push model with demand-based backpressure,
+// following the Reactive Streams (https://www.reactive-streams.org) semantics:
+// a Subscriber receives at most as many elements as it has requested through its Subscription.
+//
+// reactive 是基于需求的背压推送模型, 遵循 Reactive Streams 语义:
+// 订阅者通过 Subscription.Request(n) 声明需求, 发布者推送的元素个数不会超过需求.
+package reactive
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrInvalidRequest is signaled by OnError when Request is called with n <= 0.
+// Request(n) 的 n 不大于 0 时, 通过 OnError 通知该错误
+var ErrInvalidRequest = errors.New("reactive: request must be positive")
+
+// Unbounded can be requested to receive all elements without backpressure.
+// 请求所有元素, 不再限制需求
+const Unbounded = math.MaxInt64
+
+// Publisher is a provider of elements, which are pushed to Subscribers on demand.
+// 发布者, 按订阅者的需求推送元素
+type Publisher[T any] interface {
+	// Subscribe 订阅. 发布者首先调用 OnSubscribe, 然后按需求调用 OnNext, 最后调用 OnError 或 OnComplete 之一
+	Subscribe(Subscriber[T])
+}
+
+// Subscriber receives the signals of a Publisher, the signals are never sent concurrently.
+// 订阅者, 接收发布者的信号, 信号不会被并发调用
+type Subscriber[T any] interface {
+	// OnSubscribe 第一个信号, 订阅者通过 Subscription 请求元素
+	OnSubscribe(Subscription)
+	// OnNext 接收一个元素, 个数不会超过请求的总数
+	OnNext(T)
+	// OnError 出错结束, 之后不会再有信号
+	OnError(error)
+	// OnComplete 正常结束, 之后不会再有信号
+	OnComplete()
+}
+
+// Subscription links a Subscriber to a Publisher.
+// Request and Cancel can be called from any goroutine, including inside OnNext.
+// 订阅关系. Request 和 Cancel 可以在任意 goroutine 中调用, 包括在 OnNext 中
+type Subscription interface {
+	// Request 增加 n 个需求. 需求累加到 Unbounded 后不再限制
+	Request(n int64)
+	// Cancel 取消订阅, 之后发布者会停止发送信号. 多次调用没有影响
+	Cancel()
+}
+
+// PublisherFunc adapts a function to a Publisher.
+// 将函数转换为 Publisher
+type PublisherFunc[T any] func(Subscriber[T])
+
+// Subscribe calls f(s).
+func (f PublisherFunc[T]) Subscribe(s Subscriber[T]) {
+	f(s)
+}
+
+// addDemand 累加需求, 溢出时为 Unbounded
+func addDemand(requested, n int64) int64 {
+	if requested > Unbounded-n {
+		return Unbounded
+	}
+	return requested + n
+}
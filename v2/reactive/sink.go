@@ -0,0 +1,148 @@
+package reactive
+
+import (
+	"context"
+	"iter"
+	"sync"
+)
+
+// ToSeq subscribes to the Publisher when the Seq is iterated, requesting one element at a time.
+// It panics with the error signaled by OnError.
+// When the loop stops early, the Subscription is cancelled.
+// 迭代序列时订阅发布者, 每次请求一个元素. 发布者出错时 panic; 提前结束时取消订阅
+func ToSeq[T any](p Publisher[T]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		for v, err := range ToSeq2(p) {
+			if err != nil {
+				panic(err)
+			}
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// ToSeq2 like ToSeq, but the error is yielded as the last pair instead of panic.
+// 同 ToSeq, 但错误作为最后一对元素返回而不是 panic
+func ToSeq2[T any](p Publisher[T]) iter.Seq2[T, error] {
+	return pull(context.Background(), p)
+}
+
+// pull 订阅发布者, 每次请求一个元素. ctx 结束时序列结束
+func pull[T any](ctx context.Context, p Publisher[T]) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		sub := &pullSubscriber[T]{
+			subscribed: make(chan struct{}),
+			signals:    make(chan signal[T], 2), // 最多一个未取走的元素和一个结束信号
+		}
+		p.Subscribe(sub)
+		select {
+		case <-sub.subscribed:
+		case <-ctx.Done():
+			sub.abandon() // 之后才到达的订阅会被立即取消, 以免发布者泄漏
+			return
+		}
+		defer sub.sub.Cancel()
+		for {
+			sub.sub.Request(1)
+			var s signal[T]
+			select {
+			case s = <-sub.signals:
+			case <-ctx.Done():
+				return
+			}
+			if s.done {
+				if s.err != nil {
+					var zero T
+					yield(zero, s.err)
+				}
+				return
+			}
+			if !yield(s.val, nil) {
+				return
+			}
+		}
+	}
+}
+
+// ToChan subscribes to the Publisher and sends the elements to the returned channel,
+// which has a buffer of n elements.
+// The error channel receives the error signaled by OnError, if any.
+// Both channels are closed when the Publisher finishes or ctx is done, then the Subscription is cancelled.
+// 订阅发布者, 将元素发送到容量为 n 的通道中; 错误发送到错误通道.
+// 发布者结束或 ctx 结束时关闭两个通道并取消订阅
+func ToChan[T any](ctx context.Context, p Publisher[T], n int) (<-chan T, <-chan error) {
+	ch := make(chan T, max(n, 0))
+	errc := make(chan error, 1)
+	go func() {
+		defer close(errc)
+		defer close(ch)
+		for v, err := range pull(ctx, p) {
+			if err != nil {
+				errc <- err
+				return
+			}
+			select {
+			case ch <- v:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, errc
+}
+
+type signal[T any] struct {
+	val  T
+	err  error
+	done bool
+}
+
+// pullSubscriber 将信号转发到通道, 由迭代序列的 goroutine 读取
+type pullSubscriber[T any] struct {
+	mu         sync.Mutex
+	sub        Subscription
+	abandoned  bool // 等待订阅时 ctx 已结束
+	subscribed chan struct{}
+	signals    chan signal[T]
+}
+
+func (p *pullSubscriber[T]) OnSubscribe(s Subscription) {
+	p.mu.Lock()
+	if p.sub != nil {
+		p.mu.Unlock()
+		return
+	}
+	p.sub = s
+	abandoned := p.abandoned
+	p.mu.Unlock()
+	if abandoned {
+		s.Cancel()
+		return
+	}
+	close(p.subscribed)
+}
+
+// abandon 放弃订阅: 已收到的订阅立即取消, 之后收到的订阅在 OnSubscribe 中取消
+func (p *pullSubscriber[T]) abandon() {
+	p.mu.Lock()
+	p.abandoned = true
+	s := p.sub
+	p.mu.Unlock()
+	if s != nil {
+		s.Cancel()
+	}
+}
+
+func (p *pullSubscriber[T]) OnNext(v T) {
+	p.signals <- signal[T]{val: v}
+}
+
+func (p *pullSubscriber[T]) OnError(err error) {
+	p.signals <- signal[T]{err: err, done: true}
+}
+
+func (p *pullSubscriber[T]) OnComplete() {
+	p.signals <- signal[T]{done: true}
+}
@@ -0,0 +1,190 @@
+package reactive
+
+import (
+	"iter"
+	"sync"
+)
+
+// FromSeq creates a cold Publisher, each Subscriber iterates the Seq from the start.
+// Elements are pulled from the Seq on the goroutine which calls Request, up to the demand,
+// so a Subscriber which requests inside OnNext does not grow the call stack.
+// 从序列创建冷发布者, 每个订阅者都从头迭代序列.
+// 元素在调用 Request 的 goroutine 上按需求取出, 在 OnNext 中 Request 不会导致递归
+func FromSeq[T any](it iter.Seq[T]) Publisher[T] {
+	return PublisherFunc[T](func(s Subscriber[T]) {
+		next, stop := iter.Pull(it)
+		s.OnSubscribe(&seqSubscription[T]{sub: s, next: next, stop: stop})
+	})
+}
+
+// seqSubscription 同一时间只有一个 goroutine 在 drain 中发送信号,
+// 其他 goroutine 的 Request/Cancel 只记录状态, 由正在 drain 的 goroutine 处理
+type seqSubscription[T any] struct {
+	sub  Subscriber[T]
+	next func() (T, bool)
+	stop func()
+
+	mu        sync.Mutex
+	requested int64
+	draining  bool
+	cancelled bool
+	finished  bool
+	err       error
+}
+
+func (s *seqSubscription[T]) Request(n int64) {
+	s.mu.Lock()
+	if n <= 0 {
+		s.err = ErrInvalidRequest
+	} else {
+		s.requested = addDemand(s.requested, n)
+	}
+	s.enter()
+}
+
+func (s *seqSubscription[T]) Cancel() {
+	s.mu.Lock()
+	s.cancelled = true
+	s.enter()
+}
+
+// enter 持有锁时调用. 如果没有其他 goroutine 在 drain, 由当前 goroutine 执行 drain
+func (s *seqSubscription[T]) enter() {
+	if s.draining || s.finished {
+		s.mu.Unlock()
+		return
+	}
+	s.draining = true
+	s.mu.Unlock()
+	s.drain()
+}
+
+func (s *seqSubscription[T]) drain() {
+	for {
+		s.mu.Lock()
+		switch {
+		case s.cancelled:
+			s.finish()
+			return
+		case s.err != nil:
+			err := s.err // finish 释放锁后 s.err 可能被并发的 Request 修改
+			s.finish()
+			s.sub.OnError(err)
+			return
+		case s.requested == 0:
+			s.draining = false
+			s.mu.Unlock()
+			return
+		}
+		if s.requested != Unbounded {
+			s.requested--
+		}
+		s.mu.Unlock()
+		v, ok := s.next()
+		if !ok {
+			s.mu.Lock()
+			s.finish()
+			s.sub.OnComplete()
+			return
+		}
+		s.sub.OnNext(v)
+	}
+}
+
+// finish 持有锁时调用, 释放锁并停止迭代
+func (s *seqSubscription[T]) finish() {
+	s.finished = true
+	s.mu.Unlock()
+	s.stop()
+}
+
+// FromChan creates a hot Publisher which receives elements from the channel,
+// and completes when the channel is closed.
+// Each Subscriber receives from the channel on its own goroutine only when it has demand,
+// so Subscribers compete for the elements, and a slow Subscriber slows down the sender.
+// 从通道创建热发布者, 通道关闭时完成.
+// 每个订阅者在自己的 goroutine 中, 只在有需求时从通道接收元素; 多个订阅者竞争通道中的元素
+func FromChan[T any](ch <-chan T) Publisher[T] {
+	return PublisherFunc[T](func(s Subscriber[T]) {
+		c := &chanSubscription{
+			wake:   make(chan struct{}, 1),
+			cancel: make(chan struct{}),
+		}
+		s.OnSubscribe(c)
+		go run(c, ch, s)
+	})
+}
+
+// chanSubscription 由订阅者的 goroutine 发送所有信号
+type chanSubscription struct {
+	mu        sync.Mutex
+	requested int64
+	err       error
+	wake      chan struct{} // 需求变化时通知
+	cancel    chan struct{}
+	once      sync.Once
+}
+
+func (c *chanSubscription) Request(n int64) {
+	c.mu.Lock()
+	if n <= 0 {
+		c.err = ErrInvalidRequest
+	} else {
+		c.requested = addDemand(c.requested, n)
+	}
+	c.mu.Unlock()
+	select {
+	case c.wake <- struct{}{}:
+	default: // 已有未处理的通知
+	}
+}
+
+func (c *chanSubscription) Cancel() {
+	c.once.Do(func() {
+		close(c.cancel)
+	})
+}
+
+// take 取得一个需求, 没有需求时返回 false
+func (c *chanSubscription) take() (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return false, c.err
+	}
+	if c.requested == 0 {
+		return false, nil
+	}
+	if c.requested != Unbounded {
+		c.requested--
+	}
+	return true, nil
+}
+
+func run[T any](c *chanSubscription, ch <-chan T, s Subscriber[T]) {
+	for {
+		ok, err := c.take()
+		if err != nil {
+			s.OnError(err)
+			return
+		}
+		if !ok {
+			select {
+			case <-c.wake:
+				continue
+			case <-c.cancel:
+				return
+			}
+		}
+		select {
+		case v, open := <-ch:
+			if !open {
+				s.OnComplete()
+				return
+			}
+			s.OnNext(v)
+		case <-c.cancel:
+			return
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package stream
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy controls how MapRetry retries a failed element.
+// The zero value tries once and panics on failure.
+// 重试策略, 用于 MapRetry. 零值表示只尝试一次, 失败时 panic
+type RetryPolicy[T any] struct {
+	// MaxAttempts 每个元素最多尝试的次数(包括第一次), 不大于 0 时为 1
+	MaxAttempts int
+	// InitialBackoff 第一次重试前等待的时间, 之后每次乘以 Multiplier, 最多为 MaxBackoff(为 0 时不限制)
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// Multiplier 退避倍数, 不大于 0 时为 2
+	Multiplier float64
+	// Jitter 抖动比例 [0, 1], 每次等待的时间在 [backoff*(1-Jitter), backoff] 中随机选取
+	Jitter float64
+	// Rand 返回 [0, 1) 的随机数, 为 nil 时使用 math/rand/v2
+	Rand func() float64
+	// Sleep 等待函数, 为 nil 时使用 time.Sleep. 测试中可以替换, 避免真正等待
+	Sleep func(time.Duration)
+	// IsRetryable 判断错误是否可以重试, 为 nil 时所有错误都可以重试
+	IsRetryable func(error) bool
+	// DeadLetter 不为 nil 时, 重试耗尽(或不可重试)的元素交给它处理并跳过, 而不是 panic(*RetryError)
+	DeadLetter func(e T, err error)
+}
+
+// RetryError is the value MapRetry panics with when an element fails and there is no DeadLetter.
+// 重试耗尽(或不可重试)时 panic 的错误
+type RetryError struct {
+	Element  any   // 失败的元素
+	Attempts int   // 尝试的次数
+	Err      error // 最后一次的错误
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("stream: failed after %d attempt(s): %v", e.Attempts, e.Err)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// maxBackoff 不大于 math.MaxInt64 的最大的 float64, float64(math.MaxInt64) 会进位为 2^63
+const maxBackoff = 1<<63 - 1024
+
+// backoff 第 n 次重试(从 1 开始)前等待的时间
+func (p *RetryPolicy[T]) backoff(n int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier <= 0 {
+		multiplier = 2
+	}
+	d := float64(p.InitialBackoff) * math.Pow(multiplier, float64(n-1))
+	if math.IsNaN(d) { // 0 * +Inf
+		d = 0
+	}
+	d = min(d, maxBackoff) // 次数很大时 d 可能溢出为 +Inf, 超出范围的浮点数转换为 time.Duration 的结果是未定义的
+	if p.MaxBackoff > 0 {
+		d = min(d, float64(p.MaxBackoff))
+	}
+	if p.Jitter > 0 {
+		random := rand.Float64
+		if p.Rand != nil {
+			random = p.Rand
+		}
+		d -= d * min(p.Jitter, 1) * random()
+	}
+	return time.Duration(d)
+}
+
+// retry 调用 fn, 按策略重试. 返回结果, 尝试次数和最后一次的错误
+func retry[T, R any](p *RetryPolicy[T], e T, fn func(T) (R, error)) (R, int, error) {
+	sleep := time.Sleep
+	if p.Sleep != nil {
+		sleep = p.Sleep
+	}
+	attempt := 1
+	for {
+		r, err := fn(e)
+		if err == nil || attempt >= p.MaxAttempts || (p.IsRetryable != nil && !p.IsRetryable(err)) {
+			return r, attempt, err
+		}
+		sleep(p.backoff(attempt))
+		attempt++
+	}
+}
@@ -0,0 +1,268 @@
+package stream
+
+import (
+	"iter"
+	"math"
+	"math/bits"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// Rolling statistics over the last n elements, each yields one value per input element.
+// Before n elements are seen, the window holds all elements seen so far. n less than 1 is treated as 1.
+// 最近 n 个元素的滚动统计, 每个输入元素对应输出一个值. 不足 n 个元素时窗口包含已有的所有元素. n 小于 1 时为 1
+
+// ring 最近 n 个元素的环形缓冲区
+type ring[T any] struct {
+	items []T
+	next  int // 下一个写入的位置
+	full  bool
+}
+
+func newRing[T any](n int) *ring[T] {
+	return &ring[T]{items: make([]T, max(n, 1))}
+}
+
+// push 写入 v, 窗口已满时返回被移出的元素
+func (r *ring[T]) push(v T) (evicted T, ok bool) {
+	if r.full {
+		evicted, ok = r.items[r.next], true
+	}
+	r.items[r.next] = v
+	r.next++
+	if r.next == len(r.items) {
+		r.next, r.full = 0, true
+	}
+	return
+}
+
+func (r *ring[T]) len() int {
+	if r.full {
+		return len(r.items)
+	}
+	return r.next
+}
+
+// windowSum 最近 n 个元素之和. 整数使用 128 位补码累计, 不会溢出也不会丢失精度;
+// 浮点数使用 Neumaier 补偿求和, 单独统计无穷大和 NaN 的个数, 并且每经过一个窗口(或有限值之和溢出时)
+// 使用窗口中的元素重新计算, 所以移出的元素不会留下误差
+type windowSum[T types.Number] struct {
+	window  *ring[T]
+	isFloat bool
+	signed  bool
+	hi, lo  uint64  // 整数之和
+	sum, c  float64 // 有限浮点数之和及其补偿
+	posInf  int
+	negInf  int
+	nan     int
+}
+
+func newWindowSum[T types.Number](n int) *windowSum[T] {
+	var zero T
+	half := 0.5
+	return &windowSum[T]{window: newRing[T](n), isFloat: T(half) != zero, signed: zero-1 < zero}
+}
+
+// push 加入 e, 窗口已满时移出最早的元素
+func (w *windowSum[T]) push(e T) {
+	evicted, ok := w.window.push(e)
+	if !w.isFloat {
+		w.addInt(e, false)
+		if ok {
+			w.addInt(evicted, true)
+		}
+		return
+	}
+	if w.window.next == 0 || math.IsInf(w.sum, 0) || math.IsNaN(w.sum) {
+		w.sum, w.c, w.posInf, w.negInf, w.nan = 0, 0, 0, 0, 0
+		for _, v := range w.window.items[:w.window.len()] {
+			w.addFloat(float64(v), 1)
+		}
+		return
+	}
+	w.addFloat(float64(e), 1)
+	if ok {
+		w.addFloat(float64(evicted), -1)
+	}
+}
+
+func (w *windowSum[T]) addInt(e T, negative bool) {
+	x, ext := uint64(e), uint64(0) // ext 是高 64 位的符号扩展
+	if w.signed {
+		v := int64(e)
+		x, ext = uint64(v), uint64(v>>63)
+	}
+	var carry uint64
+	if negative {
+		w.lo, carry = bits.Sub64(w.lo, x, 0)
+		w.hi, _ = bits.Sub64(w.hi, ext, carry)
+	} else {
+		w.lo, carry = bits.Add64(w.lo, x, 0)
+		w.hi, _ = bits.Add64(w.hi, ext, carry)
+	}
+}
+
+// addFloat 加上 sign*x, sign 为 1 或 -1
+func (w *windowSum[T]) addFloat(x float64, sign int) {
+	switch {
+	case math.IsNaN(x):
+		w.nan += sign
+	case math.IsInf(x, 1):
+		w.posInf += sign
+	case math.IsInf(x, -1):
+		w.negInf += sign
+	default:
+		x *= float64(sign)
+		t := w.sum + x
+		if math.Abs(w.sum) >= math.Abs(x) {
+			w.c += (w.sum - t) + x
+		} else {
+			w.c += (x - t) + w.sum
+		}
+		w.sum = t
+	}
+}
+
+// float64 窗口内元素之和
+func (w *windowSum[T]) float64() float64 {
+	if !w.isFloat {
+		if hi := int64(w.hi); w.signed && hi < 0 { // 负数取绝对值后转换, 避免高低两部分相互抵消
+			lo, borrow := bits.Sub64(0, w.lo, 0)
+			abs, _ := bits.Sub64(0, w.hi, borrow)
+			return -(float64(abs)*0x1p64 + float64(lo))
+		}
+		return float64(w.hi)*0x1p64 + float64(w.lo)
+	}
+	switch {
+	case w.nan > 0 || w.posInf > 0 && w.negInf > 0:
+		return math.NaN()
+	case w.posInf > 0:
+		return math.Inf(1)
+	case w.negInf > 0:
+		return math.Inf(-1)
+	case math.IsInf(w.sum, 0): // 有限值之和溢出, 补偿无意义
+		return w.sum
+	}
+	return w.sum + w.c
+}
+
+// value 窗口内元素之和, 类型与元素相同. 整数溢出时按该类型回绕
+func (w *windowSum[T]) value() T {
+	if w.isFloat {
+		return T(w.float64())
+	}
+	return T(w.lo)
+}
+
+// RollingSum yields the sum of the last n elements, in the element type.
+// Integers wrap around on overflow like +; floats are summed with compensation and re-summed once per window,
+// so an evicted element leaves no error behind, and an evicted Inf or NaN no longer affects the sum.
+// 最近 n 个元素之和, 类型与元素相同. 整数溢出时与 + 一样回绕;
+// 浮点数使用补偿求和, 并且每经过一个窗口重新求和, 所以移出的元素不会留下误差, 移出的 Inf 或 NaN 也不再影响结果
+func RollingSum[T types.Number](it iter.Seq[T], n int) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		sum := newWindowSum[T](n)
+		for e := range it {
+			sum.push(e)
+			if !yield(sum.value()) {
+				return
+			}
+		}
+	}
+}
+
+// MovingAverage yields the average of the last n elements.
+// Integers are summed exactly without overflow, floats are summed as RollingSum.
+// 最近 n 个元素的平均值. 整数精确求和且不会溢出, 浮点数的求和方式同 RollingSum
+func MovingAverage[T types.Number](it iter.Seq[T], n int) iter.Seq[float64] {
+	return func(yield func(float64) bool) {
+		sum := newWindowSum[T](n)
+		for e := range it {
+			sum.push(e)
+			if !yield(sum.float64() / float64(sum.window.len())) {
+				return
+			}
+		}
+	}
+}
+
+// RollingMinMax yields the minimum (First) and maximum (Second) of the last n elements.
+// Monotonic deques keep the candidates, so the amortized cost per element is O(1).
+// 最近 n 个元素的最小值(First)和最大值(Second). 使用单调队列保存候选元素, 每个元素的均摊开销为 O(1)
+func RollingMinMax[T types.Number](it iter.Seq[T], n int) iter.Seq[types.Pair[T, T]] {
+	n = max(n, 1)
+	return func(yield func(types.Pair[T, T]) bool) {
+		// 队列中是窗口内的元素及其序号, mins 的值递增, maxs 的值递减, 队首即为最值
+		var mins, maxs deque[T]
+		i := 0
+		for e := range it {
+			mins.push(i, e, func(last T) bool { return last >= e })
+			maxs.push(i, e, func(last T) bool { return last <= e })
+			mins.expire(i - n)
+			maxs.expire(i - n)
+			i++
+			if !yield(types.Pair[T, T]{First: mins.front(), Second: maxs.front()}) {
+				return
+			}
+		}
+	}
+}
+
+type indexed[T any] struct {
+	index int
+	value T
+}
+
+// deque 单调队列, 元素按序号递增
+type deque[T any] struct {
+	items []indexed[T]
+	head  int
+}
+
+// push 从队尾移除被 v 支配的元素后加入 v
+func (d *deque[T]) push(index int, v T, dominated func(last T) bool) {
+	for len(d.items) > d.head && dominated(d.items[len(d.items)-1].value) {
+		d.items = d.items[:len(d.items)-1]
+	}
+	d.items = append(d.items, indexed[T]{index: index, value: v})
+}
+
+// expire 从队首移除序号不大于 index 的元素
+func (d *deque[T]) expire(index int) {
+	for d.head < len(d.items) && d.items[d.head].index <= index {
+		d.head++
+	}
+	// 已移除的部分过半时整理, 使内存与窗口大小成正比
+	if d.head > len(d.items)/2 {
+		d.items = append(d.items[:0], d.items[d.head:]...)
+		d.head = 0
+	}
+}
+
+func (d *deque[T]) front() T {
+	return d.items[d.head].value
+}
+
+// EWMA yields the exponentially weighted moving average: the first element, then alpha*e + (1-alpha)*previous.
+// A larger alpha discounts older elements faster. It panics if alpha is not in (0, 1].
+// 指数加权移动平均: 第一个值为第一个元素, 之后为 alpha*e + (1-alpha)*上一个值. alpha 越大旧元素的权重衰减越快.
+// alpha 不在 (0, 1] 范围内时 panic
+func EWMA[T types.Number](it iter.Seq[T], alpha float64) iter.Seq[float64] {
+	if !(alpha > 0 && alpha <= 1) {
+		panic("stream: EWMA alpha must be in (0, 1]")
+	}
+	return func(yield func(float64) bool) {
+		var avg float64
+		first := true
+		for e := range it {
+			if first {
+				avg, first = float64(e), false
+			} else {
+				avg += alpha * (float64(e) - avg)
+			}
+			if !yield(avg) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package stream
+
+import (
+	"container/heap"
+	"iter"
+	"math/rand/v2"
+)
+
+// randOf returns rng, or a randomly seeded one if rng is nil.
+// rng 为 nil 时使用随机种子的随机数生成器
+func randOf(rng *rand.Rand) *rand.Rand {
+	if rng == nil {
+		return rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+	return rng
+}
+
+// SampleFraction keeps each element with probability p independently (Bernoulli sampling).
+// A randomly seeded rng is used if rng is nil.
+// 每个元素以概率 p 被保留(伯努利采样). rng 为 nil 时使用随机种子
+func SampleFraction[T any](it iter.Seq[T], p float64, rng *rand.Rand) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		r := randOf(rng)
+		for e := range it {
+			if r.Float64() < p && !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// Shuffled collects all elements and shuffles them by Fisher–Yates.
+// A randomly seeded rng is used if rng is nil.
+// 收集所有元素后使用 Fisher–Yates 洗牌. rng 为 nil 时使用随机种子
+func Shuffled[T any](it iter.Seq[T], rng *rand.Rand) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		vals := Collect(it)
+		randOf(rng).Shuffle(len(vals), func(i, j int) {
+			vals[i], vals[j] = vals[j], vals[i]
+		})
+		for _, v := range vals {
+			if !yield(v) {
+				return
+			}
+		}
+	}
+}
+
+// ReservoirSample returns k elements chosen uniformly by reservoir sampling (all of them if there are fewer),
+// only k elements are held in memory, so it works on Seqs of unknown size.
+// The order of the result is unspecified. A randomly seeded rng is used if rng is nil.
+// (It is named ReservoirSample since Sample samples by time, see Sample.)
+// 蓄水池抽样: 从个数未知的 Seq 中等概率地抽取 k 个元素(不足 k 个时返回全部), 内存中只保存 k 个元素.
+// 结果的顺序是不确定的. rng 为 nil 时使用随机种子
+func ReservoirSample[T any](it iter.Seq[T], k int, rng *rand.Rand) []T {
+	r := randOf(rng)
+	result := make([]T, 0)
+	var n int64
+	for e := range it {
+		n++
+		if len(result) < k {
+			result = append(result, e)
+		} else if j := r.Int64N(n); j < int64(k) {
+			result[j] = e
+		}
+	}
+	return result
+}
+
+// WeightedSample chooses k elements without replacement (the A-Res algorithm),
+// each with probability proportional to its weight, elements with non-positive weight are never chosen.
+// Only k elements are held in memory, the result is in the order they are drawn.
+// A randomly seeded rng is used if rng is nil.
+// 加权不放回抽样(A-Res 算法): 元素被选中的概率与 weight 成正比, 权重不是正数的元素不会被选中.
+// 内存中只保存 k 个元素, 结果按抽中的先后排列. rng 为 nil 时使用随机种子
+func WeightedSample[T any](it iter.Seq[T], k int, weight func(T) float64, rng *rand.Rand) []T {
+	if k <= 0 {
+		return []T{}
+	}
+	r := randOf(rng)
+	h := &weightedHeap[T]{}
+	for e := range it {
+		w := weight(e)
+		if !(w > 0) {
+			continue
+		}
+		// 每个元素的键为 Exp(1)/w, 键最小的 k 个元素即为样本
+		key := r.ExpFloat64() / w
+		if h.Len() < k {
+			heap.Push(h, weighted[T]{key: key, value: e})
+		} else if key < (*h)[0].key {
+			(*h)[0] = weighted[T]{key: key, value: e}
+			heap.Fix(h, 0)
+		}
+	}
+	result := make([]T, h.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(h).(weighted[T]).value
+	}
+	return result
+}
+
+type weighted[T any] struct {
+	key   float64
+	value T
+}
+
+// weightedHeap 按 key 的最大堆
+type weightedHeap[T any] []weighted[T]
+
+func (h weightedHeap[T]) Len() int           { return len(h) }
+func (h weightedHeap[T]) Less(i, j int) bool { return h[i].key > h[j].key }
+func (h weightedHeap[T]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *weightedHeap[T]) Push(x any)        { *h = append(*h, x.(weighted[T])) }
+func (h *weightedHeap[T]) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
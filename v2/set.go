@@ -0,0 +1,109 @@
+package stream
+
+import (
+	"iter"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// Set operations by key: elements with the same key are the same set member,
+// the results are distinct by key, and follow the encounter order of the streamed side.
+// 按 key 的集合操作: key 相同的元素视为同一个成员, 结果按 key 去重, 并保持流式读取一侧的顺序
+
+// Union yields the distinct elements of a, then those of b whose key is not in a.
+// Only the keys are held in memory.
+// 并集: 先返回 a 中的不同元素, 再返回 b 中 key 不在 a 中的元素. 内存中只保存 key
+func Union[T any, K comparable](a, b iter.Seq[T], key types.Function[T, K]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		seen := make(map[K]struct{})
+		for _, it := range []iter.Seq[T]{a, b} {
+			for e := range it {
+				k := key(e)
+				if _, ok := seen[k]; ok {
+					continue
+				}
+				seen[k] = struct{}{}
+				if !yield(e) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Intersect yields the distinct elements of a whose key is in b, in the order of a.
+// The keys of b are buffered first.
+// 交集: 按 a 的顺序返回 key 在 b 中的不同元素. 先缓存 b 的 key
+func Intersect[T any, K comparable](a, b iter.Seq[T], key types.Function[T, K]) iter.Seq[T] {
+	return exceptOrIntersect(a, b, key, true)
+}
+
+// Except yields the distinct elements of a whose key is not in b, in the order of a.
+// The keys of b are buffered first.
+// 差集: 按 a 的顺序返回 key 不在 b 中的不同元素. 先缓存 b 的 key
+func Except[T any, K comparable](a, b iter.Seq[T], key types.Function[T, K]) iter.Seq[T] {
+	return exceptOrIntersect(a, b, key, false)
+}
+
+func exceptOrIntersect[T any, K comparable](a, b iter.Seq[T], key types.Function[T, K], inB bool) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		keys := make(map[K]struct{})
+		for e := range b {
+			keys[key(e)] = struct{}{}
+		}
+		seen := make(map[K]struct{})
+		for e := range a {
+			k := key(e)
+			if _, ok := keys[k]; ok != inB {
+				continue
+			}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// SymmetricDifference yields the distinct elements whose key is in exactly one of a and b:
+// those of a in the order of a, then those of b in the order of b.
+// The distinct elements of b are buffered first.
+// 对称差: 返回 key 只在 a 或 b 其中之一出现的不同元素, 先按 a 的顺序返回 a 中的, 再按 b 的顺序返回 b 中的.
+// 先缓存 b 中的不同元素
+func SymmetricDifference[T any, K comparable](a, b iter.Seq[T], key types.Function[T, K]) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var onlyB []T
+		index := make(map[K]int) // b 中的 key -> onlyB 中的下标
+		for e := range b {
+			k := key(e)
+			if _, ok := index[k]; !ok {
+				index[k] = len(onlyB)
+				onlyB = append(onlyB, e)
+			}
+		}
+		inA := make([]bool, len(onlyB))
+		seen := make(map[K]struct{})
+		for e := range a {
+			k := key(e)
+			if i, ok := index[k]; ok {
+				inA[i] = true
+				continue
+			}
+			if _, ok := seen[k]; ok {
+				continue
+			}
+			seen[k] = struct{}{}
+			if !yield(e) {
+				return
+			}
+		}
+		for i, e := range onlyB {
+			if !inA[i] && !yield(e) {
+				return
+			}
+		}
+	}
+}
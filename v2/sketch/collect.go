@@ -0,0 +1,52 @@
+package sketch
+
+import (
+	"iter"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// The CountMinSketch configuration of HeavyHitters:
+// the estimated count exceeds the true one by at most 0.1% of the total with probability 97%.
+// HeavyHitters 使用的 CountMinSketch 配置: 以 97% 的概率, 估计值最多比真实值大总数的 0.1%
+const (
+	DefaultWidth = 2048
+	DefaultDepth = 5
+)
+
+// ApproxCountDistinct counts the distinct keys of it by a HyperLogLog of the given precision.
+// The sketch is returned, call its Count method for the result, or merge and serialize it.
+// 使用给定 precision 的 HyperLogLog 统计不同 key 的个数.
+// 返回 sketch, 调用其 Count 方法获取结果, 也可以合并或序列化
+func ApproxCountDistinct[T any, K comparable](it iter.Seq[T], key types.Function[T, K], precision uint8) *HyperLogLog {
+	h := NewHyperLogLog(precision)
+	for e := range it {
+		h.AddHash(Hash(key(e)))
+	}
+	return h
+}
+
+// HeavyHitters finds the k most frequent keys of it by a TopK with DefaultWidth and DefaultDepth.
+// Keys are counted by their Hash, like ApproxCountDistinct.
+// The sketch is returned, call its Top method for the result, or merge and serialize it.
+// 使用 TopK(DefaultWidth, DefaultDepth) 统计出现次数最多的 k 个 key, 与 ApproxCountDistinct 一样按 key 的 Hash 计数.
+// 返回 sketch, 调用其 Top 方法获取结果, 也可以合并或序列化
+func HeavyHitters[T any, K comparable](it iter.Seq[T], key types.Function[T, K], k int) *TopK[K] {
+	t := NewTopK[K](k, DefaultWidth, DefaultDepth)
+	for e := range it {
+		t.Add(key(e))
+	}
+	return t
+}
+
+// ApproxQuantiles summarizes the values of it by a TDigest with DefaultCompression.
+// The sketch is returned, call its Quantiles method for the result, or merge and serialize it.
+// 使用 TDigest(DefaultCompression) 汇总 value 的分布.
+// 返回 sketch, 调用其 Quantiles 方法获取结果, 也可以合并或序列化
+func ApproxQuantiles[T any](it iter.Seq[T], value types.Function[T, float64]) *TDigest {
+	t := NewTDigest(DefaultCompression)
+	for e := range it {
+		t.Add(value(e))
+	}
+	return t
+}
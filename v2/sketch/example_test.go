@@ -0,0 +1,217 @@
+package sketch_test
+
+import (
+	"fmt"
+	"iter"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/youthlin/stream/v2/sketch"
+)
+
+// numbers yields 0..n-1
+func numbers(n int) iter.Seq[int] {
+	return func(yield func(int) bool) {
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+		}
+	}
+}
+
+func identity(i int) int { return i }
+
+// within 估计值与真实值的相对误差是否在 tolerance 以内
+func within(estimate, actual, tolerance float64) bool {
+	return math.Abs(estimate-actual) <= tolerance*actual
+}
+
+func ExampleApproxCountDistinct() {
+	// 两个分区, 其中 50000..99999 重复
+	a := sketch.ApproxCountDistinct(numbers(100000), identity, 14)
+	b := sketch.ApproxCountDistinct(func(yield func(int) bool) {
+		for i := range numbers(100000) {
+			if !yield(i + 50000) {
+				return
+			}
+		}
+	}, identity, 14)
+	fmt.Println(within(float64(a.Count()), 100000, 0.03))
+
+	data, _ := b.MarshalBinary()
+	fmt.Println(len(data))
+	var c sketch.HyperLogLog
+	fmt.Println(c.UnmarshalBinary(data))
+	fmt.Println(a.Merge(&c))
+	fmt.Println(within(float64(a.Count()), 150000, 0.03))
+
+	fmt.Println(sketch.ApproxCountDistinct(numbers(10), identity, 10).Count())
+	fmt.Println(a.Merge(sketch.NewHyperLogLog(4)))
+	// Output:
+	// true
+	// 16387
+	// <nil>
+	// <nil>
+	// true
+	// 10
+	// sketch: incompatible sketches
+}
+
+func ExampleHeavyHitters() {
+	// 第 i 个 key 出现 1000/(i+1) 次
+	words := func(from, to int) iter.Seq[string] {
+		return func(yield func(string) bool) {
+			for i := from; i < to; i++ {
+				for j := 0; j < 1000/(i+1); j++ {
+					if !yield("w" + strconv.Itoa(i)) {
+						return
+					}
+				}
+			}
+		}
+	}
+	key := func(s string) string { return s }
+	a := sketch.HeavyHitters(words(0, 500), key, 3)
+	b := sketch.HeavyHitters(words(1, 500), key, 3)
+
+	data, _ := b.MarshalBinary()
+	var c sketch.TopK[string]
+	fmt.Println(c.UnmarshalBinary(data))
+	fmt.Println(a.Merge(&c))
+	for _, item := range a.Top() {
+		fmt.Println(item.Key, item.Count)
+	}
+	// Output:
+	// <nil>
+	// <nil>
+	// w0 1000
+	// w1 1000
+	// w2 666
+}
+
+func ExampleApproxQuantiles() {
+	r := rand.New(rand.NewPCG(1, 2))
+	values := func(yield func(float64) bool) {
+		for i := 0; i < 100000; i++ {
+			if !yield(r.Float64() * 100) {
+				return
+			}
+		}
+	}
+	a := sketch.ApproxQuantiles(values, func(f float64) float64 { return f })
+	b := sketch.ApproxQuantiles(values, func(f float64) float64 { return f + 100 })
+	data, _ := b.MarshalBinary()
+	var c sketch.TDigest
+	fmt.Println(c.UnmarshalBinary(data))
+	fmt.Println(a.Merge(&c))
+	fmt.Println(a.Count())
+	for i, q := range a.Quantiles(0.01, 0.5, 0.99) {
+		fmt.Println(math.Abs(q-[]float64{2, 100, 198}[i]) < 0.5) // 误差小于值域的 0.25%
+	}
+	fmt.Println(a.Quantile(0) >= 0, a.Quantile(1) < 200)
+	fmt.Println(sketch.NewTDigest(0).Quantile(0.5))
+	// Output:
+	// <nil>
+	// <nil>
+	// 200000
+	// true
+	// true
+	// true
+	// true true
+	// NaN
+}
+
+func TestHashStable(t *testing.T) {
+	type name string
+	type pair struct {
+		A int
+		B name
+		C any
+	}
+	// the same values are pinned in the v1 sketch tests, so sketches of both versions can be merged
+	for _, c := range []struct {
+		key  any
+		want uint64
+	}{
+		{"a", 0x2c0bdbf481420f8},
+		{name("a"), 0x2c0bdbf481420f8},
+		{42, 0xa759ea27d4727622},
+		{int8(-1), 0xb4d055fcf2cbbd7b},
+		{1.5, 0xe72b41d4576e3468},
+		{true, 0x5692161d100b05e5},
+		{[2]int{1, 2}, 0x21844bc43762b697},
+		{pair{1, "x", 2.5}, 0x8eabc585f964cd4a},
+	} {
+		if got := sketch.Hash(c.key); got != c.want {
+			t.Errorf("Hash(%#v) = %#x, want %#x", c.key, got, c.want)
+		}
+	}
+}
+
+func TestTDigestConcurrentQuantile(t *testing.T) {
+	newDigest := func() *sketch.TDigest {
+		digest := sketch.NewTDigest(10)
+		for i := 0; i < 20; i++ { // fewer than the buffer size, so they are not merged yet
+			digest.Add(float64(i))
+		}
+		return digest
+	}
+	want := newDigest().Quantiles(0.1, 0.5, 0.9)
+	digest := newDigest()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if got := digest.Quantiles(0.1, 0.5, 0.9); !slices.Equal(got, want) {
+				t.Errorf("Quantiles() = %v, want %v", got, want)
+			}
+			digest.Quantile(0.5)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestHeavyHittersKeys(t *testing.T) {
+	top := sketch.HeavyHitters(numbers(10), func(i int) int { return i % 3 }, 1).Top()
+	if len(top) != 1 || top[0].Key != 0 || top[0].Count != 4 {
+		t.Errorf("Top() = %v, want [{0 4}]", top)
+	}
+	// 1 与 "1" 格式化结果相同, 但是不同的 key
+	mixed := sketch.HeavyHitters(slices.Values([]any{1, "1", "1"}), func(v any) any { return v }, 2).Top()
+	if want := []sketch.Item[any]{{Key: "1", Count: 2}, {Key: 1, Count: 1}}; !slices.Equal(mixed, want) {
+		t.Errorf("Top() = %v, want %v", mixed, want)
+	}
+	data, err := sketch.HeavyHitters(numbers(10), func(i int) int { return i % 3 }, 2).MarshalBinary()
+	var decoded sketch.TopK[int]
+	if err != nil || decoded.UnmarshalBinary(data) != nil || !slices.Equal(decoded.Top(), []sketch.Item[int]{{Key: 0, Count: 4}, {Key: 1, Count: 3}}) {
+		t.Errorf("UnmarshalBinary: %v, Top() = %v", err, decoded.Top())
+	}
+}
+
+func TestUnmarshalInvalid(t *testing.T) {
+	h := sketch.NewHyperLogLog(4)
+	topK := sketch.NewTopK[string](2, 16, 2)
+	topK.Add("a")
+	digest := sketch.NewTDigest(10)
+	digest.Add(1)
+	for _, m := range []interface {
+		MarshalBinary() ([]byte, error)
+		UnmarshalBinary([]byte) error
+	}{h, sketch.NewCountMinSketch(16, 2), topK, digest} {
+		data, _ := m.MarshalBinary()
+		if err := m.UnmarshalBinary(data); err != nil {
+			t.Errorf("%T: %v", m, err)
+		}
+		for _, bad := range [][]byte{nil, data[:len(data)-1], append(data, 0), {data[0] + 1, data[1]}} {
+			if err := m.UnmarshalBinary(bad); err != sketch.ErrInvalidData {
+				t.Errorf("%T: unmarshal %v, got %v", m, bad, err)
+			}
+		}
+	}
+}
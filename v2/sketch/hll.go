@@ -0,0 +1,112 @@
+package sketch
+
+import (
+	"math"
+	"math/bits"
+)
+
+// MinPrecision and MaxPrecision are the range of the precision of HyperLogLog.
+const (
+	MinPrecision = 4
+	MaxPrecision = 18
+)
+
+// HyperLogLog estimates the number of distinct elements with 2^precision bytes,
+// the standard error is about 1.04/sqrt(2^precision), e.g. 0.8% for precision 14 (16KB).
+// HyperLogLog 使用 2^precision 字节估计不同元素的个数, 标准误差约为 1.04/sqrt(2^precision),
+// 例如 precision 为 14 (16KB) 时约为 0.8%
+type HyperLogLog struct {
+	p         uint8
+	registers []uint8
+}
+
+// NewHyperLogLog creates a HyperLogLog, it panics if precision is not in [MinPrecision, MaxPrecision].
+// 创建 HyperLogLog, precision 不在 [MinPrecision, MaxPrecision] 范围内时 panic
+func NewHyperLogLog(precision uint8) *HyperLogLog {
+	if precision < MinPrecision || precision > MaxPrecision {
+		panic("sketch: HyperLogLog precision out of range")
+	}
+	return &HyperLogLog{p: precision, registers: make([]uint8, 1<<precision)}
+}
+
+// Precision returns the precision of the HyperLogLog.
+func (h *HyperLogLog) Precision() uint8 {
+	return h.p
+}
+
+// AddHash adds an element by its hash, see Hash.
+// 通过哈希值添加元素, 见 Hash
+func (h *HyperLogLog) AddHash(hash uint64) {
+	i := hash >> (64 - h.p)
+	// 剩余的位中前导 0 的个数 + 1, 末尾补 1 保证不超过 64-p+1
+	rho := uint8(bits.LeadingZeros64(hash<<h.p|1<<(h.p-1))) + 1
+	if rho > h.registers[i] {
+		h.registers[i] = rho
+	}
+}
+
+// Count returns the estimated number of distinct elements.
+// 估计的不同元素个数
+func (h *HyperLogLog) Count() uint64 {
+	m := float64(len(h.registers))
+	sum, zeros := 0.0, 0
+	for _, r := range h.registers {
+		sum += math.Ldexp(1, -int(r))
+		if r == 0 {
+			zeros++
+		}
+	}
+	var alpha float64
+	switch len(h.registers) {
+	case 16:
+		alpha = 0.673
+	case 32:
+		alpha = 0.697
+	case 64:
+		alpha = 0.709
+	default:
+		alpha = 0.7213 / (1 + 1.079/m)
+	}
+	estimate := alpha * m * m / sum
+	if estimate <= 2.5*m && zeros > 0 { // 基数较小时使用线性计数
+		estimate = m * math.Log(m/float64(zeros))
+	}
+	return uint64(estimate + 0.5)
+}
+
+// Merge merges other into h, so h counts the union of both, they must have the same precision.
+// 合并 other, 合并后 h 统计两者的并集. 两者的 precision 必须相同, 否则返回 ErrIncompatible
+func (h *HyperLogLog) Merge(other *HyperLogLog) error {
+	if h.p != other.p {
+		return ErrIncompatible
+	}
+	for i, r := range other.registers {
+		if r > h.registers[i] {
+			h.registers[i] = r
+		}
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (h *HyperLogLog) MarshalBinary() ([]byte, error) {
+	e := newEncoder(tagHyperLogLog)
+	e.buf = append(e.buf, h.p)
+	e.buf = append(e.buf, h.registers...)
+	return e.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (h *HyperLogLog) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, tagHyperLogLog)
+	p := d.next(1)
+	if d.err != nil || p[0] < MinPrecision || p[0] > MaxPrecision {
+		return ErrInvalidData
+	}
+	registers := d.next(1 << p[0])
+	if err := d.finish(); err != nil {
+		return err
+	}
+	h.p, h.registers = p[0], append([]uint8(nil), registers...)
+	return nil
+}
@@ -0,0 +1,217 @@
+package sketch
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultCompression is the compression used by NewTDigest when the given one is not positive.
+const DefaultCompression = 100
+
+// TDigest estimates quantiles with at most about 2*compression centroids (the merging t-digest).
+// Centroids near the tails are kept small, so extreme quantiles like 0.001 or 0.999 are more accurate than the median.
+// TDigest 使用最多约 2*compression 个质心估计分位数(merging t-digest).
+// 靠近两端的质心较小, 所以 0.001, 0.999 等极端分位数比中位数更准确
+type TDigest struct {
+	compression float64
+	centroids   []centroid // 按均值有序
+	buffer      []centroid // 未合并的元素
+	total       float64
+	min, max    float64
+}
+
+type centroid struct {
+	mean, weight float64
+}
+
+// NewTDigest creates a TDigest, DefaultCompression is used if compression is not positive.
+// 创建 TDigest, compression 不是正数时使用 DefaultCompression
+func NewTDigest(compression float64) *TDigest {
+	if !(compression > 0) {
+		compression = DefaultCompression
+	}
+	return &TDigest{compression: compression, min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// Compression returns the compression of the TDigest.
+func (t *TDigest) Compression() float64 {
+	return t.compression
+}
+
+// Add adds a value, NaN is ignored.
+// 添加一个值, 忽略 NaN
+func (t *TDigest) Add(x float64) {
+	t.AddWeighted(x, 1)
+}
+
+// AddWeighted adds a value with a weight, NaN values and non-positive weights are ignored.
+// 添加带权重的值, 忽略 NaN 以及权重不是正数的值
+func (t *TDigest) AddWeighted(x, weight float64) {
+	if math.IsNaN(x) || !(weight > 0) {
+		return
+	}
+	t.add(centroid{mean: x, weight: weight}, x, x)
+}
+
+func (t *TDigest) add(c centroid, min, max float64) {
+	t.buffer = append(t.buffer, c)
+	t.total += c.weight
+	t.min = math.Min(t.min, min)
+	t.max = math.Max(t.max, max)
+	if len(t.buffer) >= int(5*t.compression) {
+		t.flush()
+	}
+}
+
+// flush 合并缓冲区, 复制结果以释放多余的容量
+func (t *TDigest) flush() {
+	t.centroids = append([]centroid(nil), t.compressed()...)
+	t.buffer = t.buffer[:0]
+}
+
+// compressed 返回合并缓冲区后的质心, 不修改 t: 将缓冲区与已有质心按均值排序后从左到右合并,
+// 合并后质心的权重不超过 4*total*q*(1-q)/compression, q 为质心中点的累计权重比例
+func (t *TDigest) compressed() []centroid {
+	if len(t.buffer) == 0 {
+		return t.centroids
+	}
+	all := make([]centroid, 0, len(t.buffer)+len(t.centroids))
+	all = append(append(all, t.buffer...), t.centroids...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+	merged := all[:1]
+	sofar := 0.0 // merged 中除最后一个质心外的累计权重
+	for _, c := range all[1:] {
+		last := &merged[len(merged)-1]
+		weight := last.weight + c.weight
+		q := (sofar + weight/2) / t.total
+		if weight <= 4*t.total*q*(1-q)/t.compression {
+			last.mean += (c.mean - last.mean) * c.weight / weight
+			last.weight = weight
+		} else {
+			sofar += last.weight
+			merged = append(merged, c)
+		}
+	}
+	return merged
+}
+
+// Count returns the total weight of added values.
+// 添加的值的总权重
+func (t *TDigest) Count() float64 {
+	return t.total
+}
+
+// Min returns the smallest added value, or +Inf if empty.
+// 最小值, 没有值时返回 +Inf
+func (t *TDigest) Min() float64 {
+	return t.min
+}
+
+// Max returns the largest added value, or -Inf if empty.
+// 最大值, 没有值时返回 -Inf
+func (t *TDigest) Max() float64 {
+	return t.max
+}
+
+// Quantile returns the estimated value at quantile q in [0, 1], or NaN if empty or q is out of range.
+// It does not modify t: values not merged yet are merged into a copy on each call,
+// so it is safe to call Quantile, Quantiles and MarshalBinary concurrently, but not with Add or Merge.
+// 估计分位数 q([0, 1]) 处的值, 没有值或 q 超出范围时返回 NaN.
+// 不修改 t: 每次调用都在副本上合并未合并的值, 所以可以并发调用 Quantile, Quantiles 和 MarshalBinary, 但不能与 Add 或 Merge 并发
+func (t *TDigest) Quantile(q float64) float64 {
+	return t.quantile(t.compressed(), q)
+}
+
+// quantile 使用质心 centroids 估计分位数 q 处的值
+func (t *TDigest) quantile(centroids []centroid, q float64) float64 {
+	if len(centroids) == 0 || !(q >= 0 && q <= 1) {
+		return math.NaN()
+	}
+	if q == 0 {
+		return t.min
+	}
+	if q == 1 {
+		return t.max
+	}
+	index := q * t.total
+	// 每个质心的权重视为均匀分布在其均值两侧, 质心之间线性插值; 第一个和最后一个质心与 min, max 插值
+	first := centroids[0]
+	if index < first.weight/2 {
+		return t.min + (first.mean-t.min)*index/(first.weight/2)
+	}
+	sofar := first.weight / 2 // 当前质心中点的累计权重
+	for i := 0; i+1 < len(centroids); i++ {
+		left, right := centroids[i], centroids[i+1]
+		step := (left.weight + right.weight) / 2
+		if index < sofar+step {
+			return left.mean + (right.mean-left.mean)*(index-sofar)/step
+		}
+		sofar += step
+	}
+	last := centroids[len(centroids)-1]
+	return last.mean + (t.max-last.mean)*math.Min(1, (index-sofar)/(last.weight/2))
+}
+
+// Quantiles returns the estimated values at each quantile, see Quantile.
+// 估计各个分位数处的值, 见 Quantile
+func (t *TDigest) Quantiles(qs ...float64) []float64 {
+	centroids := t.compressed()
+	result := make([]float64, len(qs))
+	for i, q := range qs {
+		result[i] = t.quantile(centroids, q)
+	}
+	return result
+}
+
+// Merge merges other into t, t keeps its own compression.
+// 合并 other, t 保持自己的 compression
+func (t *TDigest) Merge(other *TDigest) error {
+	if other.total == 0 {
+		return nil
+	}
+	for _, cs := range [][]centroid{other.centroids, other.buffer} {
+		for _, c := range cs {
+			t.add(c, other.min, other.max)
+		}
+	}
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (t *TDigest) MarshalBinary() ([]byte, error) {
+	centroids := t.compressed()
+	e := newEncoder(tagTDigest)
+	e.float64(t.compression)
+	e.float64(t.min)
+	e.float64(t.max)
+	e.uint32(uint32(len(centroids)))
+	for _, c := range centroids {
+		e.float64(c.mean)
+		e.float64(c.weight)
+	}
+	return e.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (t *TDigest) UnmarshalBinary(data []byte) error {
+	d := newDecoder(data, tagTDigest)
+	compression, min, max := d.float64(), d.float64(), d.float64()
+	n := d.uint32()
+	if d.err != nil || !(compression > 0) || uint64(n) > uint64(len(d.buf)/16) {
+		return ErrInvalidData
+	}
+	centroids := make([]centroid, n)
+	total := 0.0
+	for i := range centroids {
+		centroids[i] = centroid{mean: d.float64(), weight: d.float64()}
+		if !(centroids[i].weight > 0) || i > 0 && centroids[i].mean < centroids[i-1].mean {
+			return ErrInvalidData
+		}
+		total += centroids[i].weight
+	}
+	if err := d.finish(); err != nil {
+		return err
+	}
+	*t = TDigest{compression: compression, centroids: centroids, total: total, min: min, max: max}
+	return nil
+}
@@ -0,0 +1,311 @@
+package stream
+
+import (
+	"errors"
+	"iter"
+	"time"
+
+	"github.com/youthlin/stream/v2/clock"
+)
+
+// ErrTimeout is yielded by Timeout when the next element does not arrive in time.
+var ErrTimeout = errors.New("stream: timeout")
+
+// Throttle yields an element, then drops the following elements until d has passed since it,
+// the time is read from clk, use clock.System() for the real time.
+// 节流: 返回一个元素后, 丢弃之后 d 时间内到达的元素. 时间来自 clk, 真实时间使用 clock.System()
+func Throttle[T any](it iter.Seq[T], d time.Duration, clk clock.Clock) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var last time.Time
+		first := true
+		for e := range it {
+			now := clk.Now()
+			if !first && now.Sub(last) < d {
+				continue
+			}
+			first, last = false, now
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// Debounce yields an element only when no other element arrives within d after it,
+// the last element is yielded when the source ends.
+// The source is iterated on another goroutine, see the note of the time-based operators below.
+// 防抖: 一个元素之后 d 时间内没有新元素时才返回该元素; 数据源结束时返回最后一个元素
+func Debounce[T any](it iter.Seq[T], d time.Duration, clk clock.Clock) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		p := startPull(it)
+		defer p.stop()
+		var (
+			pending T
+			timer   clock.Timer
+		)
+		for p.request(); ; {
+			select {
+			case r := <-p.resp:
+				r.rethrow()
+				if timer != nil && fired(timer.C()) { // 定时器先于元素触发
+					timer = nil
+					if !yield(pending) {
+						return
+					}
+				}
+				if !r.ok {
+					if timer != nil {
+						yield(pending)
+					}
+					return
+				}
+				if timer != nil {
+					timer.Stop()
+				}
+				pending, timer = r.val, clk.NewTimer(d)
+				p.request()
+			case <-timerC(timer):
+				timer = nil
+				if !yield(pending) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Sample yields the latest element of every period d, periods without element yield nothing.
+// The element received in the last unfinished period is dropped.
+// 采样: 每个周期 d 返回该周期内最新的元素, 没有元素的周期不返回; 最后一个未结束的周期中的元素被丢弃
+func Sample[T any](it iter.Seq[T], d time.Duration, clk clock.Clock) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		p := startPull(it)
+		defer p.stop()
+		var (
+			latest T
+			has    bool
+		)
+		next := clk.Now().Add(d) // 当前周期的结束时间, 周期按固定间隔划分, 不受处理耗时影响
+		tick := clk.NewTimer(d)
+		defer func() { tick.Stop() }()
+		emit := func() bool {
+			now := clk.Now()
+			for !next.After(now) {
+				next = next.Add(d)
+			}
+			tick = clk.NewTimer(next.Sub(now))
+			if !has {
+				return true
+			}
+			has = false
+			return yield(latest)
+		}
+		for p.request(); ; {
+			select {
+			case r := <-p.resp:
+				r.rethrow()
+				if fired(tick.C()) && !emit() {
+					return
+				}
+				if !r.ok {
+					return
+				}
+				latest, has = r.val, true
+				p.request()
+			case <-tick.C():
+				if !emit() {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Timeout yields (element, nil) pairs, if the first element does not arrive within d after the iteration starts,
+// or the next one does not arrive within d after the previous, it yields (zero, ErrTimeout) and ends.
+// 超时: 返回 (元素, nil); 如果第一个元素在开始迭代后 d 时间内、或下一个元素在上一个元素之后 d 时间内没有到达,
+// 返回 (零值, ErrTimeout) 并结束
+func Timeout[T any](it iter.Seq[T], d time.Duration, clk clock.Clock) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		p := startPull(it)
+		defer p.stop()
+		for {
+			timer := clk.NewTimer(d)
+			p.request()
+			var zero T
+			select {
+			case r := <-p.resp:
+				r.rethrow()
+				if fired(timer.C()) {
+					yield(zero, ErrTimeout)
+					return
+				}
+				timer.Stop()
+				if !r.ok {
+					return
+				}
+				if !yield(r.val, nil) {
+					return
+				}
+			case <-timer.C():
+				yield(zero, ErrTimeout)
+				return
+			}
+		}
+	}
+}
+
+// BufferTime groups elements into batches, a batch is yielded when d has passed since its first element,
+// or when it has maxSize elements. maxSize <= 0 means no size limit.
+// Empty batches are never yielded, the last batch is yielded when the source ends.
+// 按时间分批: 批次中第一个元素到达 d 时间后, 或批次达到 maxSize 个元素时返回该批次(maxSize 不大于 0 表示不限制).
+// 不会返回空批次; 数据源结束时返回最后一个批次
+func BufferTime[T any](it iter.Seq[T], d time.Duration, maxSize int, clk clock.Clock) iter.Seq[[]T] {
+	return func(yield func([]T) bool) {
+		p := startPull(it)
+		defer p.stop()
+		var (
+			batch []T
+			timer clock.Timer
+		)
+		flush := func() bool {
+			if timer != nil {
+				timer.Stop()
+				timer = nil
+			}
+			b := batch
+			batch = nil
+			return yield(b)
+		}
+		for p.request(); ; {
+			select {
+			case r := <-p.resp:
+				r.rethrow()
+				if timer != nil && fired(timer.C()) && !flush() {
+					return
+				}
+				if !r.ok {
+					if len(batch) > 0 {
+						flush()
+					}
+					return
+				}
+				if len(batch) == 0 {
+					timer = clk.NewTimer(d)
+				}
+				batch = append(batch, r.val)
+				if len(batch) == maxSize && !flush() {
+					return
+				}
+				p.request()
+			case <-timerC(timer):
+				timer = nil
+				if !flush() {
+					return
+				}
+			}
+		}
+	}
+}
+
+// region time-based operators helpers
+//
+// Debounce, Sample, Timeout and BufferTime iterate the source on another goroutine,
+// one element at a time when requested, so that a timer can fire while the source is blocked,
+// e.g. a Seq reading from a channel.
+// When both an element and a timer are ready, the timer is handled first,
+// so with a clock.Fake advanced by the source itself, the result is deterministic.
+// When the consumer stops, the goroutine exits after the blocked source yields or ends.
+// A panic in the source is re-raised on the consuming goroutine.
+// 这些操作在另一个 goroutine 中按需逐个取出数据源的元素, 使数据源阻塞时定时器仍可触发.
+// 元素和定时器同时就绪时, 先处理定时器, 所以由数据源自己推进 clock.Fake 时结果是确定的.
+// 消费者结束后, 该 goroutine 会在数据源返回下一个元素或结束时退出; 数据源的 panic 在消费者的 goroutine 上重新抛出
+
+type pulled[T any] struct {
+	val      T
+	ok       bool
+	panicked bool
+	panicVal any
+}
+
+// puller 在单独的 goroutine 中按请求逐个取出元素
+type puller[T any] struct {
+	req  chan struct{}
+	resp chan pulled[T]
+	done chan struct{}
+}
+
+func startPull[T any](it iter.Seq[T]) *puller[T] {
+	p := &puller[T]{
+		req:  make(chan struct{}),
+		resp: make(chan pulled[T]),
+		done: make(chan struct{}),
+	}
+	go func() {
+		next, stop := iter.Pull(it)
+		defer stop()
+		for {
+			select {
+			case <-p.req:
+			case <-p.done:
+				return
+			}
+			r := pullNext(next)
+			select {
+			case p.resp <- r:
+			case <-p.done:
+				return
+			}
+			if !r.ok {
+				return
+			}
+		}
+	}()
+	return p
+}
+
+func pullNext[T any](next func() (T, bool)) (r pulled[T]) {
+	defer func() {
+		if v := recover(); v != nil {
+			r = pulled[T]{panicked: true, panicVal: v}
+		}
+	}()
+	r.val, r.ok = next()
+	return
+}
+
+// rethrow 在消费者的 goroutine 上重新抛出数据源的 panic
+func (r pulled[T]) rethrow() {
+	if r.panicked {
+		panic(r.panicVal)
+	}
+}
+
+// request 请求下一个元素, 结果从 resp 读取
+func (p *puller[T]) request() {
+	p.req <- struct{}{}
+}
+
+func (p *puller[T]) stop() {
+	close(p.done)
+}
+
+// fired 定时器是否已触发
+func fired(c <-chan time.Time) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// timerC 定时器的通道, 没有定时器时返回 nil, 在 select 中永远不会就绪
+func timerC(t clock.Timer) <-chan time.Time {
+	if t == nil {
+		return nil
+	}
+	return t.C()
+}
+
+// endregion
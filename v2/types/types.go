@@ -1,6 +1,9 @@
 package types
 
-import "iter"
+import (
+	"iter"
+	"time"
+)
 
 type Optional[T any] interface {
 	Get() (T, bool)
@@ -76,12 +79,29 @@ type Int interface {
 		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
 }
 
+// 浮点数类型
+type Float interface {
+	~float32 | ~float64
+}
+
+// 数字类型
+type Number interface {
+	Int | Float
+}
+
 // Pair 表示一对关联元素
 type Pair[T, R any] struct {
 	First  T
 	Second R
 }
 
+// Window 时间窗口 [Start, End) 及其中的元素
+type Window[T any] struct {
+	Start time.Time
+	End   time.Time
+	Items []T
+}
+
 // ReverseOrder .
 // 反转顺序
 func ReverseOrder[T any](cmp Comparator[T]) Comparator[T] {
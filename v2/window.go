@@ -0,0 +1,186 @@
+package stream
+
+import (
+	"iter"
+	"slices"
+	"time"
+
+	"github.com/youthlin/stream/v2/types"
+)
+
+// WindowOption configures the watermark and the lateness policy of the event-time windows.
+// 配置事件时间窗口的水位线和迟到策略
+type WindowOption func(*windowOptions)
+
+type windowOptions struct {
+	outOfOrderness time.Duration
+	lateness       time.Duration
+}
+
+// WithMaxOutOfOrderness sets how far the timestamps may be out of order:
+// the watermark is the max timestamp seen minus d, a window is yielded when the watermark reaches its End.
+// Default 0, i.e. timestamps are expected in order.
+// 设置时间戳最大乱序程度: 水位线为已见到的最大时间戳减去 d, 水位线到达窗口的 End 时返回该窗口. 默认为 0
+func WithMaxOutOfOrderness(d time.Duration) WindowOption {
+	return func(o *windowOptions) {
+		o.outOfOrderness = d
+	}
+}
+
+// WithAllowedLateness keeps a window for d after the watermark reaches its End.
+// A late element arriving in this period is added to the window, and the window is yielded again with all its items.
+// Elements arriving later are dropped. Default 0.
+// 窗口在水位线到达 End 后再保留 d. 这期间迟到的元素会加入窗口, 窗口会带着全部元素再次返回; 更晚的元素被丢弃. 默认为 0
+func WithAllowedLateness(d time.Duration) WindowOption {
+	return func(o *windowOptions) {
+		o.lateness = d
+	}
+}
+
+// TumblingWindow groups elements into fixed-size, non-overlapping windows by the timestamp of each element,
+// windows are aligned to multiples of size since the zero time, see time.Time.Truncate.
+// Windows are yielded in order of End when the watermark passes them, the rest are yielded when the source ends.
+// Empty windows are never yielded. It panics if size is not positive.
+// 按元素自身的时间戳分到固定大小、不重叠的窗口中, 窗口按 size 对齐(见 time.Time.Truncate).
+// 水位线越过窗口时按 End 顺序返回窗口, 数据源结束时返回其余窗口; 不会返回空窗口. size 不是正数时 panic
+func TumblingWindow[T any](it iter.Seq[T], size time.Duration, timestamp types.Function[T, time.Time], opts ...WindowOption) iter.Seq[types.Window[T]] {
+	if size <= 0 {
+		panic("stream: TumblingWindow size must be positive")
+	}
+	return SlidingWindow(it, size, size, timestamp, opts...)
+}
+
+// SlidingWindow groups elements into windows of length size which start every slide,
+// so an element belongs to about size/slide windows. Windows are aligned like TumblingWindow.
+// It panics if size or slide is not positive.
+// 滑动窗口: 每隔 slide 开始一个长度为 size 的窗口, 一个元素属于约 size/slide 个窗口. 窗口对齐方式同 TumblingWindow.
+// size 或 slide 不是正数时 panic
+func SlidingWindow[T any](it iter.Seq[T], size, slide time.Duration, timestamp types.Function[T, time.Time], opts ...WindowOption) iter.Seq[types.Window[T]] {
+	if size <= 0 || slide <= 0 {
+		panic("stream: SlidingWindow size and slide must be positive")
+	}
+	return windows(it, timestamp, opts, func(ws []*window[T], ts time.Time, e T) []*window[T] {
+		for start := ts.Truncate(slide); ts.Sub(start) < size; start = start.Add(-slide) {
+			i, found := slices.BinarySearchFunc(ws, start, func(w *window[T], start time.Time) int {
+				return w.Start.Compare(start)
+			})
+			if !found {
+				ws = slices.Insert(ws, i, &window[T]{Window: types.Window[T]{Start: start, End: start.Add(size)}})
+			}
+			ws[i].add(e)
+		}
+		return ws
+	})
+}
+
+// SessionWindow groups elements into sessions: a session is [first timestamp, last timestamp + gap),
+// an element within the session extends it, and sessions that come to overlap are merged. It panics if gap is not positive.
+// 会话窗口: 会话为 [第一个时间戳, 最后一个时间戳 + gap), 落在会话中的元素会延长会话, 重叠的会话会合并. gap 不是正数时 panic
+func SessionWindow[T any](it iter.Seq[T], gap time.Duration, timestamp types.Function[T, time.Time], opts ...WindowOption) iter.Seq[types.Window[T]] {
+	if gap <= 0 {
+		panic("stream: SessionWindow gap must be positive")
+	}
+	return windows(it, timestamp, opts, func(ws []*window[T], ts time.Time, e T) []*window[T] {
+		w := &window[T]{Window: types.Window[T]{Start: ts, End: ts.Add(gap)}}
+		w.add(e)
+		i, _ := slices.BinarySearchFunc(ws, ts, func(w *window[T], start time.Time) int {
+			return w.Start.Compare(start)
+		})
+		ws = slices.Insert(ws, i, w)
+		// 与前后重叠的会话合并
+		if i > 0 && ws[i-1].End.After(ws[i].Start) {
+			i--
+		}
+		for i+1 < len(ws) && ws[i].End.After(ws[i+1].Start) {
+			ws[i].merge(ws[i+1])
+			ws = slices.Delete(ws, i+1, i+2)
+		}
+		return ws
+	})
+}
+
+// window 窗口状态
+type window[T any] struct {
+	types.Window[T]
+	fired bool // 已经返回过
+	dirty bool // 返回后又有迟到的元素加入, 需要再次返回
+}
+
+func (w *window[T]) add(e T) {
+	w.Items = append(w.Items, e)
+	w.dirty = true
+}
+
+func (w *window[T]) merge(other *window[T]) {
+	w.Items = append(w.Items, other.Items...)
+	w.End = maxTime(w.End, other.End)
+	w.fired = w.fired || other.fired
+	w.dirty = true
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// windows 事件时间窗口的公共部分: 维护水位线, 丢弃过晚的元素, 按 End 顺序返回到期的窗口.
+// assign 将元素加入(或创建、合并)窗口, ws 按 Start 排序
+func windows[T any](it iter.Seq[T], timestamp types.Function[T, time.Time], opts []WindowOption,
+	assign func(ws []*window[T], ts time.Time, e T) []*window[T]) iter.Seq[types.Window[T]] {
+	var o windowOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return func(yield func(types.Window[T]) bool) {
+		var (
+			ws        []*window[T]
+			maxTs     time.Time
+			watermark time.Time // 零值早于所有时间戳
+			started   bool
+		)
+		// emit 返回水位线已到达的窗口, 清理保留时间已过的窗口
+		emit := func(final bool) bool {
+			var ready []*window[T]
+			for _, w := range ws {
+				if w.dirty && (final || !watermark.Before(w.End)) {
+					ready = append(ready, w)
+				}
+			}
+			slices.SortStableFunc(ready, func(a, b *window[T]) int {
+				return a.End.Compare(b.End)
+			})
+			for _, w := range ready {
+				w.fired, w.dirty = true, false
+				out := w.Window
+				out.Items = slices.Clip(out.Items)
+				if !yield(out) {
+					return false
+				}
+			}
+			ws = slices.DeleteFunc(ws, func(w *window[T]) bool {
+				return !watermark.Before(w.End.Add(o.lateness))
+			})
+			return true
+		}
+		for e := range it {
+			ts := timestamp(e)
+			if !started || ts.After(maxTs) {
+				started, maxTs = true, ts
+			}
+			ws = assign(ws, ts, e)
+			// 过晚的元素: 为其新建的窗口已超过保留时间, 丢弃
+			ws = slices.DeleteFunc(ws, func(w *window[T]) bool {
+				return !w.fired && !watermark.Before(w.End.Add(o.lateness))
+			})
+			if wm := maxTs.Add(-o.outOfOrderness); wm.After(watermark) {
+				watermark = wm
+			}
+			if !emit(false) {
+				return
+			}
+		}
+		emit(true)
+	}
+}